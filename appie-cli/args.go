@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cmdArgs holds a subcommand's positional arguments and --flags. Flags may
+// appear anywhere and take the forms --name, --name=value and --name value;
+// names passed as boolFlags to parseArgs never consume the next argument.
+type cmdArgs struct {
+	pos   []string
+	flags map[string]string
+}
+
+func parseArgs(args []string, boolFlags ...string) cmdArgs {
+	isBool := map[string]bool{}
+	for _, name := range boolFlags {
+		isBool[name] = true
+		isBool["no-"+name] = true
+	}
+
+	a := cmdArgs{flags: map[string]string{}}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "--") || arg == "--" {
+			a.pos = append(a.pos, arg)
+			continue
+		}
+		name, value, hasValue := strings.Cut(arg[2:], "=")
+		if !hasValue && !isBool[name] && i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") {
+			value = args[i+1]
+			i++
+		}
+		a.flags[name] = value
+	}
+	return a
+}
+
+// arg returns the i-th positional argument, or "" when absent.
+func (a cmdArgs) arg(i int) string {
+	if i < len(a.pos) {
+		return a.pos[i]
+	}
+	return ""
+}
+
+// argInt returns the i-th positional argument as an int, or def when absent.
+func (a cmdArgs) argInt(i, def int) int {
+	if n, err := strconv.Atoi(a.arg(i)); err == nil {
+		return n
+	}
+	return def
+}
+
+func (a cmdArgs) has(name string) bool {
+	_, ok := a.flags[name]
+	return ok
+}
+
+func (a cmdArgs) str(name, def string) string {
+	if v, ok := a.flags[name]; ok && v != "" {
+		return v
+	}
+	return def
+}
+
+func (a cmdArgs) num(name string, def int) int {
+	if n, err := strconv.Atoi(a.flags[name]); err == nil {
+		return n
+	}
+	return def
+}
+
+// flag reads a boolean flag: --name and --name=true enable it, --no-name and
+// --name=false disable it.
+func (a cmdArgs) flag(name string, def bool) bool {
+	if _, ok := a.flags["no-"+name]; ok {
+		return false
+	}
+	v, ok := a.flags[name]
+	if !ok {
+		return def
+	}
+	if v == "" {
+		return true
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+// list splits a comma separated flag value.
+func (a cmdArgs) list(name string) []string {
+	var out []string
+	for _, v := range strings.Split(a.flags[name], ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// parseSince reads a --since value: a date (2026-01-31) or a period back from
+// now in days or weeks (14d, 4w).
+func parseSince(v string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(dateLayout, v); err == nil {
+		return t, nil
+	}
+	if len(v) > 1 {
+		n, err := strconv.Atoi(v[:len(v)-1])
+		if err == nil {
+			switch v[len(v)-1] {
+			case 'd':
+				return now.AddDate(0, 0, -n), nil
+			case 'w':
+				return now.AddDate(0, 0, -7*n), nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q (use YYYY-MM-DD, 14d or 4w)", v)
+}
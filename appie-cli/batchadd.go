@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	appie "github.com/gwillem/appie-go"
+)
+
+// maxListQuantity is the largest quantity batch-add passes on.
+const maxListQuantity = 99
+
+// batchEntry is one element of batch-add's input: a product ID, a free text
+// item or a product name still to be resolved. Qty is a pointer so a missing
+// qty (default 1) can be told apart from an explicit 0.
+type batchEntry struct {
+	ID    int    `json:"id"`
+	Text  string `json:"text"`
+	Name  string `json:"name"`
+	Qty   *int   `json:"qty"`
+	Input string `json:"-"`
+}
+
+// batchLine is the pre-flight verdict on one input entry. Status is ok,
+// clamped (quantity changed), substituted, unavailable, unknown (no such
+// product), ambiguous (name not resolved, see candidates), unsafe (declares
+// an allergen from config.json), invalid (neither id, name nor text) or
+// error (lookup failed).
+type batchLine struct {
+	Index         int               `json:"index"`
+	Input         string            `json:"input,omitempty"`
+	Name          string            `json:"name,omitempty"`
+	ID            int               `json:"id,omitempty"`
+	Text          string            `json:"text,omitempty"`
+	Title         string            `json:"title,omitempty"`
+	Source        string            `json:"source,omitempty"`
+	Qty           int               `json:"qty"`
+	Status        string            `json:"status"`
+	ReplacementID int               `json:"replacementId,omitempty"`
+	Candidates    []resolvedProduct `json:"candidates,omitempty"`
+	Error         string            `json:"error,omitempty"`
+
+	product appie.Product
+}
+
+// valid reports whether the line can be sent to the list.
+func (l batchLine) valid() bool {
+	return l.Status == "ok" || l.Status == "clamped" || l.Status == "substituted"
+}
+
+// unresolved reports whether the line still needs a name resolved.
+func (l batchLine) unresolved() bool {
+	return l.ID == 0 && l.Text == "" && l.Name != "" && l.valid()
+}
+
+// item returns the list item to send for a valid line.
+func (l batchLine) item() appie.ListItem {
+	if l.ID == 0 {
+		return appie.ListItem{Name: l.Text, Quantity: l.Qty}
+	}
+	if l.ReplacementID > 0 {
+		return appie.ListItem{ProductID: l.ReplacementID, Quantity: l.Qty}
+	}
+	return appie.ListItem{ProductID: l.ID, Quantity: l.Qty}
+}
+
+// batchLines checks the shape and quantity of every entry.
+func batchLines(entries []batchEntry) []batchLine {
+	lines := make([]batchLine, len(entries))
+	for i, e := range entries {
+		l := batchLine{Index: i, Input: e.Input, Name: strings.TrimSpace(e.Name), ID: e.ID, Text: e.Text, Qty: 1, Status: "ok"}
+		if e.Qty != nil {
+			l.Qty = min(max(*e.Qty, 1), maxListQuantity)
+			if l.Qty != *e.Qty {
+				l.Status = "clamped"
+			}
+		}
+		if e.ID > 0 {
+			l.Text, l.Name = "", ""
+		} else if strings.TrimSpace(e.Text) != "" {
+			l.Name = ""
+		} else if l.Name == "" {
+			l.Status, l.Error = "invalid", "needs an id, a name or a text"
+		}
+		lines[i] = l
+	}
+	return lines
+}
+
+// resolveNames turns the name lines into product IDs: product-cache.json
+// first, then a search. policy decides what happens when the search returns
+// more than one product: first-match takes the first safe, orderable hit,
+// interactive asks on the terminal and report leaves the line ambiguous with
+// its candidates.
+func resolveNames(ctx context.Context, client *appie.Client, safety *safetyFilter, lines []batchLine, policy string) error {
+	cache, err := loadProductCache()
+	if err != nil {
+		return err
+	}
+	resolver := &productResolver{client: client, cache: cache, safety: safety}
+	var tty *os.File
+	if policy == "interactive" {
+		if tty, err = os.OpenFile("/dev/tty", os.O_RDWR, 0); err != nil {
+			return fmt.Errorf("--interactive needs a terminal: %w", err)
+		}
+		defer tty.Close()
+	}
+	for i := range lines {
+		l := &lines[i]
+		if !l.unresolved() {
+			continue
+		}
+		if id, ok := cache.lookup(l.Name); ok {
+			l.ID, l.Source = id, "cache"
+			continue
+		}
+		cands, err := resolver.candidates(ctx, l.Name, 5)
+		switch {
+		case err != nil:
+			l.Status, l.Error = "error", err.Error()
+		case len(cands) == 0:
+			l.Status, l.Error = "unknown", fmt.Sprintf("no product found for %q", l.Name)
+		case len(cands) == 1 || policy == "first-match":
+			l.ID, l.Source = cands[0].ID, "search"
+		case policy == "interactive":
+			if c := chooseCandidate(tty, *l, cands); c != nil {
+				l.ID, l.Source = c.ID, "choice"
+				continue
+			}
+			fallthrough
+		default:
+			l.Status, l.Candidates = "ambiguous", cands
+			l.Error = fmt.Sprintf("%d products match %q; pass the id or --first-match", len(cands), l.Name)
+		}
+	}
+	return nil
+}
+
+// chooseCandidate asks on tty which candidate line means. An empty answer
+// takes the first; anything that is not a listed number skips the line.
+func chooseCandidate(tty *os.File, line batchLine, cands []resolvedProduct) *resolvedProduct {
+	fmt.Fprintf(tty, "%d x %q:\n", line.Qty, line.Name)
+	for i, c := range cands {
+		fmt.Fprintf(tty, "  %d) %s [%d]\n", i+1, c.Title, c.ID)
+	}
+	fmt.Fprint(tty, "Choose 1-", len(cands), " (enter = 1, s = skip): ")
+	answer, _ := bufio.NewReader(tty).ReadString('\n')
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return &cands[0]
+	}
+	n, err := strconv.Atoi(answer)
+	if err != nil || n < 1 || n > len(cands) {
+		return nil
+	}
+	return &cands[n-1]
+}
+
+// preflight looks up every product concurrently and marks lines whose product
+// is unknown (404) or cannot be ordered. Any other failure (expired login,
+// network, 5xx) says nothing about the products and is returned instead.
+func preflight(ctx context.Context, client *appie.Client, lines []batchLine) error {
+	errs := make([]error, len(lines))
+	parallel(len(lines), 8, func(i int) {
+		l := &lines[i]
+		if l.ID == 0 || !l.valid() {
+			return
+		}
+		d, err := getProductDetails(ctx, client, l.ID)
+		switch {
+		case err != nil && isNotFound(err):
+			l.Status, l.Error = "unknown", fmt.Sprintf("no product with id %d", l.ID)
+		case err != nil:
+			errs[i] = err
+		case !(d.IsOrderable || d.IsAvailable):
+			l.Status, l.Title, l.product = "unavailable", d.Title, d.Product
+		default:
+			l.Title, l.product = d.Title, d.Product
+		}
+	})
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isNotFound reports whether err is getREST's 404: no such product.
+func isNotFound(err error) bool {
+	var he *httpError
+	return errors.As(err, &he) && he.Status == http.StatusNotFound
+}
+
+// allergyCheck marks lines whose product declares an allergen from
+// config.json, or lists it in its ingredients, as unsafe. It costs a detail
+// lookup per product, so it only runs when allergies are configured.
+func allergyCheck(ctx context.Context, client *appie.Client, safety *safetyFilter, lines []batchLine) {
+	if !safety.hasAllergies() {
+		return
+	}
+	parallel(len(lines), 8, func(i int) {
+		l := &lines[i]
+		if l.ID == 0 || !l.valid() {
+			return
+		}
+		id := l.ID
+		if l.ReplacementID > 0 {
+			id = l.ReplacementID
+		}
+		if c := safety.checkDeclared(ctx, client, id); len(c) > 0 {
+			l.Status, l.Error = "unsafe", describeConflicts(c)
+		}
+	})
+}
+
+// substituteLines replaces unknown and unavailable products with the closest
+// available alternative, reporting each replacement as a substitution.
+func substituteLines(ctx context.Context, client *appie.Client, safety *safetyFilter, lines []batchLine) []substitution {
+	var subs []substitution
+	for i := range lines {
+		l := &lines[i]
+		if l.Status != "unknown" && l.Status != "unavailable" {
+			continue
+		}
+		if l.ID == 0 {
+			// A name that matched nothing: there is no product to replace.
+			continue
+		}
+		orig := l.product
+		if orig.ID == 0 {
+			orig = knownProduct(l.ID)
+		}
+		cands, err := findSubstitutes(ctx, client, safety, orig)
+		if err != nil || len(cands) == 0 {
+			continue
+		}
+		original := orig.Title
+		if original == "" {
+			original = fmt.Sprintf("product %d", l.ID)
+		}
+		subs = append(subs, substitution{
+			ProductID:     l.ID,
+			Original:      original,
+			ReplacementID: cands[0].ProductID,
+			Replacement:   cands[0].Title,
+			Quantity:      l.Qty,
+			Rule:          l.Status,
+		})
+		l.Status, l.ReplacementID, l.Title = "substituted", cands[0].ProductID, cands[0].Title
+	}
+	return subs
+}
+
+func runBatchAdd(ctx context.Context, configPath string, argv []string) {
+	// Reads from stdin: a JSON array ([{"id": 123, "qty": 2}, {"text": "free text", "qty": 1}]),
+	// CSV or plain text lines ("2 halfvolle melk"); see readBatchInput.
+	args := parseArgs(argv, "substitute", "strict", "dry-run", "first-match", "interactive", "safe")
+	entries, err := readBatchInput(os.Stdin, args.str("format", "auto"))
+	if err != nil {
+		fatal("Invalid input: %v", err)
+	}
+	if len(entries) == 0 {
+		fatal("No valid items in input")
+	}
+	client := mustAuth(ctx, configPath)
+	cfg := mustSkillConfig()
+
+	lines := batchLines(entries)
+	safety := newSafetyFilter(cfg)
+	policy := "report"
+	if args.flag("interactive", false) {
+		policy = "interactive"
+	} else if args.flag("first-match", false) {
+		policy = "first-match"
+	}
+	if err := resolveNames(ctx, client, safety, lines, policy); err != nil {
+		fatal("%v", err)
+	}
+	if err := preflight(ctx, client, lines); err != nil {
+		fatal("Batch add failed: %v", err)
+	}
+	var subs []substitution
+	if args.flag("substitute", true) {
+		subs = substituteLines(ctx, client, safety, lines)
+	}
+	if args.flag("safe", true) {
+		allergyCheck(ctx, client, safety, lines)
+	}
+
+	var items []appie.ListItem
+	report := false
+	invalid := 0
+	for _, l := range lines {
+		if l.Status != "ok" || l.Source != "" {
+			report = true // show what changed and what each name resolved to
+		}
+		if l.valid() {
+			items = append(items, l.item())
+		} else {
+			invalid++
+		}
+	}
+	// Butcher notes replace meat products before anything is reported, so
+	// --dry-run shows the list as it would be added.
+	items, butchered, err := newButcherRules(cfg.ButcherItems).apply(ctx, client, items)
+	if err != nil {
+		fatal("Batch add failed: %v", err)
+	}
+	subs = append(subs, butchered...)
+
+	// Invalid lines are skipped, as batch-add always did; --strict adds
+	// nothing unless every line is valid.
+	if args.flag("dry-run", false) || invalid > 0 && args.flag("strict", false) {
+		result := map[string]any{"ok": invalid == 0, "added": 0, "items": lines}
+		if len(subs) > 0 {
+			result["substitutions"] = subs
+		}
+		printJSON(result)
+		if invalid > 0 && !args.flag("dry-run", false) {
+			fmt.Fprintf(os.Stderr, "%d invalid items, nothing added; fix them or drop --strict\n", invalid)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(items) == 0 {
+		fatal("No valid items in input")
+	}
+
+	if err := client.AddToShoppingList(ctx, items); err != nil {
+		fatal("Batch add failed: %v", err)
+	}
+	known := make([]appie.Product, 0, len(lines))
+	for _, l := range lines {
+		known = append(known, l.product)
+	}
+	recordAdditions(ctx, client, "batch-add", listQuantities(items), known...)
+	if len(subs) == 0 && !report {
+		fmt.Printf(`{"ok": true, "added": %d}`+"\n", len(items))
+		return
+	}
+	result := map[string]any{
+		"ok":    true,
+		"added": len(items),
+	}
+	if invalid > 0 {
+		result["skipped"] = invalid
+	}
+	if len(subs) > 0 {
+		result["substitutions"] = subs
+	}
+	if report {
+		result["items"] = lines
+	}
+	printJSON(result)
+}
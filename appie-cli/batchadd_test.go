@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	appie "github.com/gwillem/appie-go"
+)
+
+func intPtr(n int) *int { return &n }
+
+func TestBatchLines(t *testing.T) {
+	tests := []struct {
+		entry  batchEntry
+		qty    int
+		status string
+	}{
+		{batchEntry{ID: 1}, 1, "ok"},
+		{batchEntry{ID: 1, Qty: intPtr(3)}, 3, "ok"},
+		{batchEntry{ID: 1, Qty: intPtr(0)}, 1, "clamped"},
+		{batchEntry{ID: 1, Qty: intPtr(150)}, maxListQuantity, "clamped"},
+		{batchEntry{Text: "brood van de bakker"}, 1, "ok"},
+		{batchEntry{Name: "melk", Qty: intPtr(2)}, 2, "ok"},
+		{batchEntry{Name: "  "}, 1, "invalid"},
+		{batchEntry{}, 1, "invalid"},
+	}
+	entries := make([]batchEntry, len(tests))
+	for i, tt := range tests {
+		entries[i] = tt.entry
+	}
+	for i, l := range batchLines(entries) {
+		if l.Qty != tests[i].qty || l.Status != tests[i].status {
+			t.Errorf("batchLines(%+v) = qty %d %s, want qty %d %s", tests[i].entry, l.Qty, l.Status, tests[i].qty, tests[i].status)
+		}
+	}
+}
+
+// productServer answers product lookups with the status per product ID.
+func productServer(t *testing.T, status map[string]int) *appie.Client {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		code := status[id]
+		if code != http.StatusOK {
+			http.Error(w, `{"message":"nope"}`, code)
+			return
+		}
+		orderable := id != "3"
+		w.Write([]byte(`{"productCard":{"webshopId":` + id + `,"title":"Product ` + id + `","isOrderable":` + strconv.FormatBool(orderable) + `}}`))
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("APPIE_REST_URL", srv.URL)
+	return appie.New(appie.WithTokens("token", ""))
+}
+
+func TestPreflight(t *testing.T) {
+	client := productServer(t, map[string]int{"1": 200, "2": 404, "3": 200})
+	lines := batchLines([]batchEntry{{ID: 1}, {ID: 2}, {ID: 3}, {Text: "vrije tekst"}})
+	if err := preflight(context.Background(), client, lines); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ok", "unknown", "unavailable", "ok"}
+	for i, l := range lines {
+		if l.Status != want[i] {
+			t.Errorf("line %d: status %s (%s), want %s", i, l.Status, l.Error, want[i])
+		}
+	}
+	if lines[0].Title != "Product 1" {
+		t.Errorf("line 0: title %q, want Product 1", lines[0].Title)
+	}
+}
+
+func TestPreflightErrors(t *testing.T) {
+	for _, code := range []int{401, 403, 500} {
+		client := productServer(t, map[string]int{"1": 200, "2": code})
+		lines := batchLines([]batchEntry{{ID: 1}, {ID: 2}})
+		if err := preflight(context.Background(), client, lines); err == nil {
+			t.Errorf("preflight with a %d: no error, lines %+v", code, lines)
+		}
+		if lines[1].Status == "unknown" {
+			t.Errorf("preflight with a %d marked the product unknown", code)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// readBatchInput reads batch-add's input from r. format is json, csv, text
+// or auto, which picks json for a JSON array, csv when the first line has a
+// comma, semicolon or tab, and text otherwise.
+func readBatchInput(r io.Reader, format string) ([]batchEntry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\ufeff")))
+	if format == "auto" {
+		format = detectBatchFormat(string(data))
+	}
+	switch format {
+	case "json":
+		var entries []batchEntry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, err
+		}
+		return entries, nil
+	case "csv":
+		return parseBatchCSV(string(data))
+	case "text":
+		return parseBatchText(string(data)), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q (use json, csv or text)", format)
+	}
+}
+
+func detectBatchFormat(data string) string {
+	if strings.HasPrefix(data, "[") {
+		return "json"
+	}
+	first, _, _ := strings.Cut(data, "\n")
+	if strings.ContainsAny(first, ",;\t") {
+		return "csv"
+	}
+	return "text"
+}
+
+// batchColumns maps CSV header names, English and Dutch, to entry fields.
+var batchColumns = map[string]string{
+	"id": "id", "product_id": "id", "productid": "id",
+	"name": "name", "product": "name", "naam": "name",
+	"text": "text", "note": "text", "tekst": "text",
+	"qty": "qty", "quantity": "qty", "aantal": "qty",
+}
+
+// parseBatchCSV reads rows with an optional header of id, name, text and qty
+// columns. Without a header a row is read cell by cell: a number next to a
+// name is the quantity, a lone number or the first of two is a product ID.
+func parseBatchCSV(data string) ([]batchEntry, error) {
+	first, _, _ := strings.Cut(data, "\n")
+	cr := csv.NewReader(strings.NewReader(data))
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	switch {
+	case strings.Contains(first, "\t"):
+		cr.Comma = '\t'
+	case strings.Count(first, ";") > strings.Count(first, ","):
+		cr.Comma = ';'
+	}
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	var header []string
+	for _, cell := range rows[0] {
+		col, ok := batchColumns[strings.ToLower(strings.TrimSpace(cell))]
+		if !ok {
+			header = nil
+			break
+		}
+		header = append(header, col)
+	}
+	if header != nil {
+		rows = rows[1:]
+	}
+
+	var entries []batchEntry
+	for _, row := range rows {
+		e := batchEntry{Input: strings.Join(row, string(cr.Comma))}
+		if header != nil {
+			for i, cell := range row {
+				if i < len(header) {
+					setBatchField(&e, header[i], strings.TrimSpace(cell))
+				}
+			}
+		} else {
+			setBatchCells(&e, row)
+		}
+		if e.ID == 0 && e.Name == "" && e.Text == "" && e.Qty == nil {
+			continue // blank row
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func setBatchField(e *batchEntry, field, value string) {
+	if value == "" {
+		return
+	}
+	switch field {
+	case "id":
+		e.ID, _ = strconv.Atoi(value)
+		if e.ID == 0 {
+			e.Name = value
+		}
+	case "name":
+		e.Name = value
+	case "text":
+		e.Text = value
+	case "qty":
+		if n, err := strconv.Atoi(value); err == nil {
+			e.Qty = &n
+		}
+	}
+}
+
+func setBatchCells(e *batchEntry, row []string) {
+	var nums []int
+	for _, cell := range row {
+		cell = strings.TrimSpace(cell)
+		if cell == "" {
+			continue
+		}
+		if n, err := strconv.Atoi(cell); err == nil {
+			nums = append(nums, n)
+		} else if e.Name == "" {
+			e.Name = cell
+		}
+	}
+	switch {
+	case e.Name != "" && len(nums) > 0:
+		e.Qty = &nums[0]
+	case len(nums) > 0:
+		e.ID = nums[0]
+		if len(nums) > 1 {
+			e.Qty = &nums[1]
+		}
+	}
+	if isButcherNote(e.Name) {
+		e.Text, e.Name = e.Name, ""
+	}
+}
+
+var batchTextLine = regexp.MustCompile(`^(\d+)\s*[x×]?\s+(.+)$`)
+
+// parseBatchText reads one item per line: "2 halfvolle melk", "2x melk",
+// "melk" (quantity 1) or a product ID. List bullets and # comments are
+// ignored; "text:" and butcher notes become free text items.
+func parseBatchText(data string) []batchEntry {
+	var entries []batchEntry
+	for _, line := range strings.Split(data, "\n") {
+		s := strings.TrimSpace(line)
+		s = strings.TrimSpace(strings.TrimLeft(s, "-*•"))
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		e := batchEntry{Input: strings.TrimSpace(line)}
+		if m := batchTextLine.FindStringSubmatch(s); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			e.Qty, s = &n, strings.TrimSpace(m[2])
+		}
+		if rest, ok := strings.CutPrefix(s, "text:"); ok {
+			e.Text = strings.TrimSpace(rest)
+		} else if id, err := strconv.Atoi(s); err == nil {
+			e.ID = id
+		} else if isButcherNote(s) {
+			e.Text = s
+		} else {
+			e.Name = s
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// entrySummary flattens an entry for comparison; qty 0 means none given.
+type entrySummary struct {
+	ID   int
+	Name string
+	Text string
+	Qty  int
+}
+
+func summarize(entries []batchEntry) []entrySummary {
+	out := make([]entrySummary, len(entries))
+	for i, e := range entries {
+		out[i] = entrySummary{ID: e.ID, Name: e.Name, Text: e.Text}
+		if e.Qty != nil {
+			out[i].Qty = *e.Qty
+		}
+	}
+	return out
+}
+
+func TestParseBatchText(t *testing.T) {
+	text := `# boodschappen
+2 halfvolle melk
+- 3x bananen
+* melk
+• 12 × eieren
+197393
+text: brood van de bakker
+🥩 Slager: kipfilet (voor Curry)
+
+`
+	want := []entrySummary{
+		{Name: "halfvolle melk", Qty: 2},
+		{Name: "bananen", Qty: 3},
+		{Name: "melk"},
+		{Name: "eieren", Qty: 12},
+		{ID: 197393},
+		{Text: "brood van de bakker"},
+		{Text: "🥩 Slager: kipfilet (voor Curry)"},
+	}
+	if got := summarize(parseBatchText(text)); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseBatchText:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseBatchCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []entrySummary
+	}{
+		{"header", "id,qty\n197393,2\n8817,1", []entrySummary{{ID: 197393, Qty: 2}, {ID: 8817, Qty: 1}}},
+		{"dutch header", "naam;aantal\nmelk;2\nkaas;1", []entrySummary{{Name: "melk", Qty: 2}, {Name: "kaas", Qty: 1}}},
+		{"name in id column", "id,qty\nmelk,2", []entrySummary{{Name: "melk", Qty: 2}}},
+		{"no header", "melk,2\n197393\n197393,3", []entrySummary{{Name: "melk", Qty: 2}, {ID: 197393}, {ID: 197393, Qty: 3}}},
+		{"tabs", "2\tmelk\n\t\n1\tkaas", []entrySummary{{Name: "melk", Qty: 2}, {Name: "kaas", Qty: 1}}},
+		{"butcher note", "Slager: gehakt,1", []entrySummary{{Text: "Slager: gehakt", Qty: 1}}},
+	}
+	for _, tt := range tests {
+		entries, err := parseBatchCSV(tt.data)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := summarize(entries); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReadBatchInput(t *testing.T) {
+	tests := []struct {
+		data   string
+		format string
+		want   []entrySummary
+	}{
+		{`[{"id": 1, "qty": 2}, {"text": "tekst"}]`, "auto", []entrySummary{{ID: 1, Qty: 2}, {Text: "tekst"}}},
+		{"\ufeffid,qty\n1,2", "auto", []entrySummary{{ID: 1, Qty: 2}}},
+		{"2 melk\nkaas", "auto", []entrySummary{{Name: "melk", Qty: 2}, {Name: "kaas"}}},
+		{"melk, halfvol", "text", []entrySummary{{Name: "melk, halfvol"}}},
+	}
+	for _, tt := range tests {
+		entries, err := readBatchInput(strings.NewReader(tt.data), tt.format)
+		if err != nil {
+			t.Errorf("readBatchInput(%q): %v", tt.data, err)
+			continue
+		}
+		if got := summarize(entries); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("readBatchInput(%q) = %+v, want %+v", tt.data, got, tt.want)
+		}
+	}
+	if _, err := readBatchInput(strings.NewReader("melk"), "xml"); err == nil {
+		t.Error("readBatchInput with format xml: no error")
+	}
+}
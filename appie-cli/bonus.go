@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	appie "github.com/gwillem/appie-go"
+)
+
+// bonusProduct is a product from getBonusProducts.
+type bonusProduct struct {
+	ID               int     `json:"webshopId"`
+	Title            string  `json:"title"`
+	Brand            string  `json:"brand"`
+	Category         string  `json:"mainCategory"`
+	SubCategory      string  `json:"subCategory"`
+	UnitSize         string  `json:"salesUnitSize"`
+	CurrentPrice     float64 `json:"currentPrice"`
+	PriceBeforeBonus float64 `json:"priceBeforeBonus"`
+	BonusMechanism   string  `json:"bonusMechanism"`
+}
+
+// getBonusProductsTyped runs getBonusProducts and decodes the products.
+func getBonusProductsTyped(ctx context.Context, client *appie.Client, size int) ([]bonusProduct, error) {
+	raw, err := getBonusProducts(ctx, client, size)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Products []bonusProduct `json:"products"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("parse error: %w", err)
+	}
+	return result.Products, nil
+}
+
+var (
+	percentOffRe = regexp.MustCompile(`(\d+)\s*%\s*korting`)
+	buyGetFreeRe = regexp.MustCompile(`(\d+)\s*\+\s*(\d+)\s*gratis`)
+)
+
+// savings estimates the discount per package. The API only fills
+// priceBeforeBonus for straight price cuts; multi-buy deals ("1+1 gratis",
+// "2e halve prijs") are estimated from the mechanism text.
+func (p bonusProduct) savings() float64 {
+	if p.PriceBeforeBonus > p.CurrentPrice && p.CurrentPrice > 0 {
+		return p.PriceBeforeBonus - p.CurrentPrice
+	}
+	price := p.PriceBeforeBonus
+	if price == 0 {
+		price = p.CurrentPrice
+	}
+	return price * bonusDiscountRate(p.BonusMechanism)
+}
+
+// bonusDiscountRate estimates the fraction saved per package for a bonus
+// mechanism, assuming the user buys enough to trigger it.
+func bonusDiscountRate(mechanism string) float64 {
+	m := strings.ToLower(mechanism)
+	if sub := percentOffRe.FindStringSubmatch(m); sub != nil {
+		pct, _ := strconv.Atoi(sub[1])
+		return float64(pct) / 100
+	}
+	if sub := buyGetFreeRe.FindStringSubmatch(m); sub != nil {
+		buy, _ := strconv.Atoi(sub[1])
+		free, _ := strconv.Atoi(sub[2])
+		if buy+free > 0 {
+			return float64(free) / float64(buy+free)
+		}
+	}
+	switch {
+	case strings.Contains(m, "2e halve prijs"):
+		return 0.25
+	case strings.Contains(m, "2e gratis"):
+		return 0.5
+	}
+	return 0
+}
+
+// ingredientStopwords are preparation words that never appear in product
+// titles and would block a match ("fijngesneden ui" should match "Uien").
+var ingredientStopwords = map[string]bool{
+	"vers": true, "verse": true, "gesneden": true, "fijngesneden": true,
+	"grof": true, "fijn": true, "gehakte": true, "gekookte": true,
+	"bevroren": true, "diepvries": true, "gedroogde": true, "gemalen": true,
+	"kleine": true, "grote": true, "middelgrote": true, "rijpe": true,
+	"naar": true, "smaak": true, "en": true, "of": true, "van": true,
+	"de": true, "het": true, "een": true, "met": true,
+}
+
+// matchTokens splits an ingredient name into the words a product title must
+// contain.
+func matchTokens(s string) []string {
+	var tokens []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r > 127 || r == '-')
+	}) {
+		if !ingredientStopwords[w] && len(w) > 1 {
+			tokens = append(tokens, w)
+		}
+	}
+	return tokens
+}
+
+// matchesTitle reports whether every token occurs in title. Short tokens must
+// start a word ("ui" matches "Uien" but not "Fruit").
+func matchesTitle(tokens []string, title string) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+	title = strings.ToLower(title)
+	for _, t := range tokens {
+		if len([]rune(t)) <= 3 {
+			if !containsWordPrefix(title, t) {
+				return false
+			}
+		} else if !strings.Contains(title, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// bonusMatch is a recipe ingredient that is on bonus.
+type bonusMatch struct {
+	Ingredient     string  `json:"ingredient"`
+	ProductID      int     `json:"productId"`
+	Title          string  `json:"title"`
+	Price          float64 `json:"price"`
+	WasPrice       float64 `json:"wasPrice,omitempty"`
+	BonusMechanism string  `json:"bonusMechanism,omitempty"`
+	Savings        float64 `json:"savings"`
+}
+
+// bonusMatcher matches recipe ingredients to this week's bonus products.
+type bonusMatcher struct {
+	products []bonusProduct
+}
+
+// newBonusMatcher indexes bonus products, skipping anything the user buys at
+// the butcher: a bonus on kipfilet is no use when the kip comes from the slager.
+func newBonusMatcher(products []bonusProduct, butcher butcherRules) *bonusMatcher {
+	m := &bonusMatcher{}
+	for _, p := range products {
+		if _, ok := butcher.match(p.Title, p.Category); ok {
+			continue
+		}
+		m.products = append(m.products, p)
+	}
+	return m
+}
+
+// match returns the best bonus product for an ingredient: the one with the
+// shortest (most specific) title, then the largest saving.
+func (m *bonusMatcher) match(ing recipeIngredient) (bonusMatch, bool) {
+	tokens := matchTokens(ing.name())
+	var best *bonusProduct
+	for i, p := range m.products {
+		if !matchesTitle(tokens, p.Title) {
+			continue
+		}
+		if best == nil || len(p.Title) < len(best.Title) ||
+			len(p.Title) == len(best.Title) && p.savings() > best.savings() {
+			best = &m.products[i]
+		}
+	}
+	if best == nil {
+		return bonusMatch{}, false
+	}
+	return bonusMatch{
+		Ingredient:     ing.name(),
+		ProductID:      best.ID,
+		Title:          best.Title,
+		Price:          best.CurrentPrice,
+		WasPrice:       best.PriceBeforeBonus,
+		BonusMechanism: best.BonusMechanism,
+		Savings:        roundCents(best.savings()),
+	}, true
+}
+
+// matchRecipe returns the bonus products used by a recipe. A product matched
+// by several ingredients is only counted once.
+func (m *bonusMatcher) matchRecipe(r *recipeDetails) []bonusMatch {
+	var matches []bonusMatch
+	seen := map[int]bool{}
+	for _, ing := range r.Ingredients {
+		bm, ok := m.match(ing)
+		if !ok || seen[bm.ProductID] {
+			continue
+		}
+		seen[bm.ProductID] = true
+		matches = append(matches, bm)
+	}
+	return matches
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,64 @@
+package main
+
+import "testing"
+
+func TestBonusDiscountRate(t *testing.T) {
+	tests := []struct {
+		mechanism string
+		want      float64
+	}{
+		{"25% korting", 0.25},
+		{"40 % KORTING", 0.4},
+		{"1+1 gratis", 0.5},
+		{"2 + 1 gratis", 1.0 / 3},
+		{"2e halve prijs", 0.25},
+		{"2e gratis", 0.5},
+		{"2 voor 3,99", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := bonusDiscountRate(tt.mechanism); got != tt.want {
+			t.Errorf("bonusDiscountRate(%q) = %g, want %g", tt.mechanism, got, tt.want)
+		}
+	}
+}
+
+func TestBonusSavings(t *testing.T) {
+	tests := []struct {
+		name string
+		p    bonusProduct
+		want float64
+	}{
+		{"price cut", bonusProduct{CurrentPrice: 1.50, PriceBeforeBonus: 2.00, BonusMechanism: "25% korting"}, 0.50},
+		{"multi-buy without old price", bonusProduct{CurrentPrice: 3.00, BonusMechanism: "1+1 gratis"}, 1.50},
+		{"multi-buy at the old price", bonusProduct{CurrentPrice: 2.00, PriceBeforeBonus: 2.00, BonusMechanism: "2e halve prijs"}, 0.50},
+		{"unknown mechanism", bonusProduct{CurrentPrice: 2.00, BonusMechanism: "2 voor 3,99"}, 0},
+	}
+	for _, tt := range tests {
+		if got := roundCents(tt.p.savings()); got != tt.want {
+			t.Errorf("%s: savings = %.2f, want %.2f", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMatchesTitle(t *testing.T) {
+	tests := []struct {
+		ingredient string
+		title      string
+		want       bool
+	}{
+		{"fijngesneden ui", "AH Uien", true},
+		{"ui", "AH Fruitsalade", false},
+		{"ei", "AH Scharrel eieren", true},
+		{"ei", "AH Romeinse sla", false},
+		{"verse basilicum", "AH Basilicum", true},
+		{"geraspte kaas", "AH Geraspte belegen kaas", true},
+		{"kipfilet", "AH Kipdijfilet", false},
+		{"naar smaak", "AH Zout", false},
+	}
+	for _, tt := range tests {
+		if got := matchesTitle(matchTokens(tt.ingredient), tt.title); got != tt.want {
+			t.Errorf("matchesTitle(%q, %q) = %v, want %v", tt.ingredient, tt.title, got, tt.want)
+		}
+	}
+}
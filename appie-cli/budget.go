@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	appie "github.com/gwillem/appie-go"
+)
+
+// budgetLine is one priced product on the list or order.
+type budgetLine struct {
+	ProductID      int           `json:"productId"`
+	Title          string        `json:"title"`
+	Brand          string        `json:"brand,omitempty"`
+	Quantity       int           `json:"quantity"`
+	Price          float64       `json:"price"`
+	WasPrice       float64       `json:"wasPrice,omitempty"`
+	IsBonus        bool          `json:"isBonus,omitempty"`
+	BonusMechanism string        `json:"bonusMechanism,omitempty"`
+	UnitSize       string        `json:"unitSize,omitempty"`
+	Total          float64       `json:"total"`
+	Alternatives   []alternative `json:"alternatives,omitempty"`
+
+	product appie.Product
+}
+
+// alternative is a cheaper option for a budgetLine. Saving is the estimated
+// saving on the whole line.
+type alternative struct {
+	Kind           string  `json:"kind"`
+	ProductID      int     `json:"productId"`
+	Title          string  `json:"title"`
+	Brand          string  `json:"brand,omitempty"`
+	Price          float64 `json:"price"`
+	UnitSize       string  `json:"unitSize,omitempty"`
+	UnitPrice      string  `json:"unitPrice,omitempty"`
+	IsBonus        bool    `json:"isBonus,omitempty"`
+	BonusMechanism string  `json:"bonusMechanism,omitempty"`
+	Saving         float64 `json:"saving"`
+}
+
+// budgetReport is the output of budget check.
+type budgetReport struct {
+	Source       string       `json:"source"`
+	Budget       float64      `json:"budget,omitempty"`
+	Total        float64      `json:"total"`
+	BonusSavings float64      `json:"bonusSavings"`
+	WithinBudget bool         `json:"withinBudget"`
+	OverBy       float64      `json:"overBy,omitempty"`
+	Lines        []budgetLine `json:"lines"`
+	Unpriced     []string     `json:"unpriced,omitempty"`
+	// PotentialSaving sums the best alternative of every line.
+	PotentialSaving float64 `json:"potentialSaving,omitempty"`
+}
+
+var unitPricePattern = regexp.MustCompile(`(\d+[.,]\d{2})`)
+var unitPriceUnit = regexp.MustCompile(`(?i)per\s+(\d*\s*[a-z]+)`)
+
+// unitPrice reads the price per unit from a product's unit price description
+// ("prijs per kg €5.99"). ok is false when it cannot be compared.
+func unitPrice(p appie.Product) (price float64, unit string, ok bool) {
+	m := unitPricePattern.FindString(p.UnitPriceDescription)
+	u := unitPriceUnit.FindStringSubmatch(p.UnitPriceDescription)
+	if m == "" || u == nil {
+		return 0, "", false
+	}
+	price, err := strconv.ParseFloat(strings.ReplaceAll(m, ",", "."), 64)
+	return price, strings.ToLower(strings.Join(strings.Fields(u[1]), " ")), err == nil && price > 0
+}
+
+func isHouseBrand(brand string) bool {
+	b := strings.ToLower(brand)
+	return b == "ah" || strings.HasPrefix(b, "ah ")
+}
+
+// lineSaving estimates what swapping line to cand saves: by unit price when
+// both have one in the same unit, else by package price.
+func lineSaving(line budgetLine, cand appie.Product) float64 {
+	op, ou, ok1 := unitPrice(line.product)
+	cp, cu, ok2 := unitPrice(cand)
+	if ok1 && ok2 && ou == cu {
+		return roundCents(line.Total * (1 - cp/op))
+	}
+	return roundCents((line.Price - cand.Price.Now) * float64(line.Quantity))
+}
+
+// classifyAlternative names how cand is cheaper than line's product: a bonus
+// variant, the AH house brand or a larger package of the same brand. Other
+// search hits are not alternatives.
+func classifyAlternative(line budgetLine, cand appie.Product) string {
+	orig := line.product
+	switch {
+	case cand.IsBonus && !orig.IsBonus:
+		return "bonus"
+	case isHouseBrand(cand.Brand) && !isHouseBrand(orig.Brand):
+		return "house-brand"
+	case strings.EqualFold(cand.Brand, orig.Brand) && cand.Price.Now > orig.Price.Now:
+		if _, _, ok := unitPrice(cand); ok {
+			return "larger-package"
+		}
+	}
+	return ""
+}
+
+// findAlternatives searches for the product without its brand and keeps the
+// best cheaper option per kind, best saving first.
+func findAlternatives(ctx context.Context, client *appie.Client, safety *safetyFilter, line budgetLine) ([]alternative, error) {
+	hits, err := client.SearchProducts(ctx, stripBrand(line.Title, line.Brand), 15)
+	if err != nil {
+		return nil, err
+	}
+	hits, _ = safety.filterProducts(hits)
+	best := map[string]alternative{}
+	for _, p := range hits {
+		if p.ID == line.ProductID || !(p.IsOrderable || p.IsAvailable) {
+			continue
+		}
+		kind := classifyAlternative(line, p)
+		if kind == "" {
+			continue
+		}
+		saving := lineSaving(line, p)
+		if saving < 0.05 || saving <= best[kind].Saving {
+			continue
+		}
+		best[kind] = alternative{
+			Kind:           kind,
+			ProductID:      p.ID,
+			Title:          p.Title,
+			Brand:          p.Brand,
+			Price:          p.Price.Now,
+			UnitSize:       p.UnitSize,
+			UnitPrice:      p.UnitPriceDescription,
+			IsBonus:        p.IsBonus,
+			BonusMechanism: p.BonusMechanism,
+			Saving:         saving,
+		}
+	}
+	out := make([]alternative, 0, len(best))
+	for _, a := range best {
+		out = append(out, a)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Saving > out[j].Saving })
+	return out, nil
+}
+
+// priceLines fetches the products for qty (product ID to quantity) and
+// returns the priced lines, most expensive first, and the IDs the API
+// returned no product for.
+func priceLines(ctx context.Context, client *appie.Client, qty map[int]int) ([]budgetLine, []int, error) {
+	ids := make([]int, 0, len(qty))
+	for id := range qty {
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, nil, nil
+	}
+	sort.Ints(ids)
+	products, err := client.GetProductsByIDs(ctx, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	priced := map[int]bool{}
+	lines := make([]budgetLine, 0, len(products))
+	for _, p := range products {
+		priced[p.ID] = true
+		lines = append(lines, budgetLine{
+			ProductID:      p.ID,
+			Title:          p.Title,
+			Brand:          p.Brand,
+			Quantity:       qty[p.ID],
+			Price:          p.Price.Now,
+			WasPrice:       p.Price.Was,
+			IsBonus:        p.IsBonus,
+			BonusMechanism: p.BonusMechanism,
+			UnitSize:       p.UnitSize,
+			Total:          roundCents(p.Price.Now * float64(qty[p.ID])),
+			product:        p,
+		})
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].Total > lines[j].Total })
+	var missing []int
+	for _, id := range ids {
+		if !priced[id] {
+			missing = append(missing, id)
+		}
+	}
+	return lines, missing, nil
+}
+
+func runBudget(ctx context.Context, configPath string, argv []string) {
+	if len(argv) < 1 || argv[0] != "check" {
+		fmt.Fprintln(os.Stderr, "Usage: appie-cli budget check [--order] [--budget <euro>] [--alternatives <n>] [--no-safe]")
+		os.Exit(1)
+	}
+	args := parseArgs(argv[1:], "order", "safe")
+	cfg := mustSkillConfig()
+	budget := cfg.WeeklyBudget
+	if v := args.str("budget", ""); v != "" {
+		b, err := strconv.ParseFloat(strings.ReplaceAll(v, ",", "."), 64)
+		if err != nil {
+			fatal("Invalid --budget %q", v)
+		}
+		budget = b
+	}
+	client := mustAuth(ctx, configPath)
+
+	report := &budgetReport{Source: "list", Budget: budget, Lines: []budgetLine{}}
+	qty := map[int]int{}
+	names := map[int]string{}
+	if args.flag("order", false) {
+		report.Source = "order"
+		order, err := client.GetOrder(ctx)
+		if err != nil {
+			fatal("Get order failed: %v", err)
+		}
+		for _, it := range order.Items {
+			qty[it.ProductID] += it.Quantity
+			if it.Product != nil {
+				names[it.ProductID] = it.Product.Title
+			}
+		}
+	} else {
+		list, err := client.GetShoppingList(ctx)
+		if err != nil {
+			fatal("Get shopping list failed: %v", err)
+		}
+		for _, it := range list.Items {
+			if it.ProductID == 0 {
+				report.Unpriced = append(report.Unpriced, it.Name)
+				continue
+			}
+			if !it.Checked {
+				qty[it.ProductID] += max(it.Quantity, 1)
+				names[it.ProductID] = it.Name
+			}
+		}
+	}
+	lines, missing, err := priceLines(ctx, client, qty)
+	if err != nil {
+		fatal("Get products failed: %v", err)
+	}
+	for _, id := range missing {
+		// Gone from the assortment: no price, but not silently dropped.
+		name := names[id]
+		if name == "" {
+			name = fmt.Sprintf("product %d", id)
+		}
+		report.Unpriced = append(report.Unpriced, name)
+	}
+	for _, l := range lines {
+		report.Total += l.Total
+		if l.IsBonus && l.WasPrice > l.Price {
+			report.BonusSavings += (l.WasPrice - l.Price) * float64(l.Quantity)
+		}
+	}
+	report.Total, report.BonusSavings = roundCents(report.Total), roundCents(report.BonusSavings)
+	report.WithinBudget = budget <= 0 || report.Total <= budget
+	if !report.WithinBudget {
+		report.OverBy = roundCents(report.Total - budget)
+		fmt.Fprintf(os.Stderr, "warning: %s total €%.2f is €%.2f over the budget of €%.2f\n", report.Source, report.Total, report.OverBy, budget)
+	}
+
+	// Alternatives cost a search per line: only look for them when over
+	// budget (or asked for), and only for the most expensive lines.
+	n := 0
+	if !report.WithinBudget || args.has("alternatives") {
+		n = min(args.num("alternatives", 5), len(lines))
+	}
+	safety := newSafetyFilter(cfg)
+	if !args.flag("safe", true) {
+		safety = &safetyFilter{}
+	}
+	parallel(n, 4, func(i int) {
+		alts, err := findAlternatives(ctx, client, safety, lines[i])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: alternatives for %s: %v\n", lines[i].Title, err)
+			return
+		}
+		lines[i].Alternatives = alts
+	})
+	for _, l := range lines {
+		if len(l.Alternatives) > 0 {
+			report.PotentialSaving += l.Alternatives[0].Saving
+		}
+	}
+	report.PotentialSaving = roundCents(report.PotentialSaving)
+	if lines != nil {
+		report.Lines = lines
+	}
+	printJSON(report)
+}
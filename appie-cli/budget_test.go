@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	appie "github.com/gwillem/appie-go"
+)
+
+func TestPriceLines(t *testing.T) {
+	// Product 3 is gone from the assortment: the API leaves it out.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"products":[
+			{"webshopId":1,"title":"AH Halfvolle melk","currentPrice":1.29},
+			{"webshopId":2,"title":"AH Basmati rijst","currentPrice":2.49}]}`))
+	}))
+	defer srv.Close()
+	client := appie.New(appie.WithBaseURL(srv.URL), appie.WithTokens("token", ""))
+
+	lines, missing, err := priceLines(context.Background(), client, map[int]int{1: 2, 2: 1, 3: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 || lines[0].ProductID != 1 || lines[0].Total != 2.58 || lines[1].ProductID != 2 {
+		t.Errorf("lines = %+v, want melk 2.58 then rijst", lines)
+	}
+	if !slices.Equal(missing, []int{3}) {
+		t.Errorf("missing = %v, want [3]", missing)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	appie "github.com/gwillem/appie-go"
+)
+
+// productCache mirrors product-cache.json: product names mapped to AH product
+// IDs, split into weekly basics and recipe ingredients.
+type productCache struct {
+	Comment     string                `json:"_comment,omitempty"`
+	Basics      map[string]productRef `json:"basics"`
+	Ingredients map[string]productRef `json:"ingredients"`
+}
+
+// productRef is a cached product ID. The agent maintains the cache by hand,
+// so both 123 and {"id": 123, ...} are accepted.
+type productRef int
+
+func (r *productRef) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '{' {
+		var obj struct {
+			ID productRef `json:"id"`
+		}
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		*r = obj.ID
+		return nil
+	}
+	n, err := strconv.Atoi(strings.Trim(string(data), `"`))
+	if err != nil {
+		return fmt.Errorf("invalid product id %s", data)
+	}
+	*r = productRef(n)
+	return nil
+}
+
+// loadProductCache reads product-cache.json; a missing file is an empty cache.
+func loadProductCache() (*productCache, error) {
+	c := &productCache{}
+	data, err := os.ReadFile(skillPath("product-cache.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("parse product-cache.json: %w", err)
+	}
+	return c, nil
+}
+
+// lookup finds a cached product ID by case-insensitive name.
+func (c *productCache) lookup(name string) (int, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, section := range []map[string]productRef{c.Ingredients, c.Basics} {
+		for k, id := range section {
+			if strings.ToLower(k) == name && id > 0 {
+				return int(id), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// resolvedProduct is a product name resolved to an AH product.
+type resolvedProduct struct {
+	ID     int    `json:"id"`
+	Title  string `json:"title,omitempty"`
+	Source string `json:"source"`
+}
+
+// productResolver turns ingredient or product names into product IDs, trying
+// product-cache.json before SearchProducts.
+type productResolver struct {
+	client *appie.Client
+	cache  *productCache
+	safety *safetyFilter
+}
+
+// resolve returns the product for name. Search results that conflict with
+// allergies or dislikes, or declare an allergen, are skipped, and orderable
+// products are preferred.
+func (r *productResolver) resolve(ctx context.Context, name string) (*resolvedProduct, error) {
+	if id, ok := r.cache.lookup(name); ok {
+		return &resolvedProduct{ID: id, Source: "cache"}, nil
+	}
+	cands, err := r.candidates(ctx, name, 5)
+	if err != nil {
+		return nil, err
+	}
+	if len(cands) == 0 {
+		return nil, fmt.Errorf("no product found for %q", name)
+	}
+	for _, c := range cands {
+		if len(r.safety.checkDeclared(ctx, r.client, c.ID)) == 0 {
+			return &c, nil
+		}
+	}
+	return nil, fmt.Errorf("every product found for %q declares an allergen from config.json", name)
+}
+
+// candidates returns up to n safe search results for name, orderable
+// products first.
+func (r *productResolver) candidates(ctx context.Context, name string, n int) ([]resolvedProduct, error) {
+	products, err := r.client.SearchProducts(ctx, name, n)
+	if err != nil {
+		return nil, err
+	}
+	products, _ = r.safety.filterProducts(products)
+	sort.SliceStable(products, func(i, j int) bool {
+		return products[i].IsOrderable && !products[j].IsOrderable
+	})
+	out := make([]resolvedProduct, len(products))
+	for i, p := range products {
+		out[i] = resolvedProduct{ID: p.ID, Title: p.Title, Source: "search"}
+	}
+	return out, nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	appie "github.com/gwillem/appie-go"
+)
+
+// The GraphQL API is undocumented and changes without notice, so every query
+// the CLI depends on lives in queries/ where `appie-cli doctor` can check it.
+// Each file starts with a header:
+//
+//	# <name> v<version> (auth: anon|member)
+//	# example: {"id": 1}
+//
+//go:embed queries/*.graphql
+var queryFiles embed.FS
+
+// catalogQuery is one embedded .graphql file.
+type catalogQuery struct {
+	Name    string
+	Version string
+	Auth    string
+	Example map[string]any
+	Text    string
+}
+
+var catalogHeader = regexp.MustCompile(`^#\s*(\S+)\s+(v\d+)\s+\(auth:\s*(anon|member)\)`)
+
+var queryCatalog = loadQueryCatalog()
+
+// loadQueryCatalog parses the embedded queries. A malformed file is a build
+// mistake, so it panics rather than failing at the first request.
+func loadQueryCatalog() map[string]catalogQuery {
+	entries, err := queryFiles.ReadDir("queries")
+	if err != nil {
+		panic(err)
+	}
+	catalog := map[string]catalogQuery{}
+	for _, e := range entries {
+		data, err := queryFiles.ReadFile(path.Join("queries", e.Name()))
+		if err != nil {
+			panic(err)
+		}
+		text := string(data)
+		m := catalogHeader.FindStringSubmatch(text)
+		if m == nil {
+			panic(fmt.Sprintf("queries/%s: missing '# <name> v<n> (auth: ...)' header", e.Name()))
+		}
+		q := catalogQuery{Name: m[1], Version: m[2], Auth: m[3], Text: text}
+		for _, line := range strings.Split(text, "\n") {
+			if v, ok := strings.CutPrefix(line, "# example:"); ok {
+				if err := json.Unmarshal([]byte(v), &q.Example); err != nil {
+					panic(fmt.Sprintf("queries/%s: bad example: %v", e.Name(), err))
+				}
+			}
+		}
+		catalog[q.Name] = q
+	}
+	return catalog
+}
+
+// gqlError is one entry of a GraphQL response's errors array.
+type gqlError struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+type graphqlErrors []gqlError
+
+func (e graphqlErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Message
+	}
+	return "GraphQL errors: " + strings.Join(msgs, "; ")
+}
+
+var brokenFieldPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`Cannot query field "([^"]+)" on type "([^"]+)"`),
+	regexp.MustCompile(`Unknown argument "([^"]+)" on field "([^"]+)"`),
+	regexp.MustCompile(`Field "([^"]+)" of type "([^"]+)" must have a selection`),
+	regexp.MustCompile(`Field "([^"]+)" must not have a selection since type "([^"]+)"`),
+}
+
+// brokenFields extracts Type.field names from schema validation errors.
+func (e graphqlErrors) brokenFields() []string {
+	seen := map[string]bool{}
+	var fields []string
+	for _, err := range e {
+		for _, re := range brokenFieldPatterns {
+			if m := re.FindStringSubmatch(err.Message); m != nil && !seen[m[2]+"."+m[1]] {
+				seen[m[2]+"."+m[1]] = true
+				fields = append(fields, m[2]+"."+m[1])
+			}
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// runCatalogQuery runs the named catalog query and decodes its data into out.
+// Any entry in the errors array fails the call, so a removed field surfaces
+// as an error instead of silently empty data.
+func runCatalogQuery(ctx context.Context, client *appie.Client, name string, variables map[string]any, out any) error {
+	q, ok := queryCatalog[name]
+	if !ok {
+		return fmt.Errorf("unknown query %q", name)
+	}
+	body, err := graphqlQuery(ctx, client, q.Text, variables)
+	if err != nil {
+		return err
+	}
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors graphqlErrors   `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("parse error: %w\nraw: %s", err, string(body))
+	}
+	if len(result.Errors) > 0 {
+		return result.Errors
+	}
+	if err := json.Unmarshal(result.Data, out); err != nil {
+		return fmt.Errorf("parse error: %w", err)
+	}
+	return nil
+}
+
+// doctorResult reports how one catalog query fared against the API.
+type doctorResult struct {
+	Query        string   `json:"query"`
+	Version      string   `json:"version"`
+	Status       string   `json:"status"`
+	BrokenFields []string `json:"brokenFields,omitempty"`
+	Errors       []string `json:"errors,omitempty"`
+}
+
+func runDoctor(ctx context.Context, configPath string) {
+	client := mustAnon(ctx, configPath)
+	names := make([]string, 0, len(queryCatalog))
+	for name := range queryCatalog {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]doctorResult, 0, len(names))
+	failed := false
+	for _, name := range names {
+		q := queryCatalog[name]
+		r := doctorResult{Query: name, Version: q.Version, Status: "ok"}
+		if q.Auth == "member" && !client.IsAuthenticated() {
+			r.Status = "skipped"
+			r.Errors = []string{"not logged in"}
+			results = append(results, r)
+			continue
+		}
+		var data json.RawMessage
+		err := runCatalogQuery(ctx, client, name, q.Example, &data)
+		if gqlErrs, ok := err.(graphqlErrors); ok {
+			r.Status, r.BrokenFields = "broken", gqlErrs.brokenFields()
+			for _, e := range gqlErrs {
+				r.Errors = append(r.Errors, e.Message)
+			}
+		} else if err != nil {
+			r.Status, r.Errors = "error", []string{err.Error()}
+		}
+		failed = failed || r.Status == "broken" || r.Status == "error"
+		results = append(results, r)
+	}
+	printJSON(map[string]any{
+		"endpoint": graphqlURL(),
+		"ok":       !failed,
+		"queries":  results,
+	})
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// skillConfig mirrors config.json (see config-template.json).
+type skillConfig struct {
+	MealsPerWeek          int    `json:"meals_per_week"`
+	MaxCookingTimeMinutes int    `json:"max_cooking_time_minutes"`
+	HouseholdSize         int    `json:"household_size"`
+	ShoppingDay           string `json:"shopping_day"`
+	ProposalDay           string `json:"proposal_day"`
+	ProposalTime          string `json:"proposal_time"`
+
+	// WeeklyBudget is the grocery budget in euros; 0 means no budget.
+	WeeklyBudget float64 `json:"weekly_budget"`
+
+	Preferences struct {
+		Healthy         bool `json:"healthy"`
+		PreferBonus     bool `json:"prefer_bonus"`
+		PreferSeasonal  bool `json:"prefer_seasonal"`
+		BudgetConscious bool `json:"budget_conscious"`
+	} `json:"preferences"`
+
+	// NutritionLimits are per person per day; MealShare is the part of
+	// them one dinner may take. See nutrition.go.
+	NutritionLimits struct {
+		Salt         float64 `json:"salt"`
+		Sugars       float64 `json:"sugars"`
+		SaturatedFat float64 `json:"saturated_fat"`
+		MealShare    float64 `json:"meal_share"`
+	} `json:"nutrition_limits"`
+
+	Dislikes     []string `json:"dislikes"`
+	Allergies    []string `json:"allergies"`
+	ButcherItems []string `json:"butcher_items"`
+
+	CuisinePreferences struct {
+		Liked    []string `json:"liked"`
+		Disliked []string `json:"disliked"`
+	} `json:"cuisine_preferences"`
+}
+
+// skillPath resolves a skill data file (config.json, meal-history.json, ...)
+// relative to APPIE_SKILL_DIR, or the working directory when unset.
+func skillPath(name string) string {
+	if dir := os.Getenv("APPIE_SKILL_DIR"); dir != "" {
+		return filepath.Join(dir, name)
+	}
+	return name
+}
+
+// loadSkillConfig reads config.json. A missing file is not an error: the
+// defaults from config-template.json are returned instead.
+func loadSkillConfig() (*skillConfig, error) {
+	cfg := &skillConfig{
+		MealsPerWeek:          3,
+		MaxCookingTimeMinutes: 30,
+		HouseholdSize:         2,
+	}
+	cfg.Preferences.Healthy = true
+	cfg.Preferences.PreferBonus = true
+	cfg.Preferences.PreferSeasonal = true
+	cfg.NutritionLimits.Salt = 6
+	cfg.NutritionLimits.Sugars = 50
+	cfg.NutritionLimits.SaturatedFat = 20
+	cfg.NutritionLimits.MealShare = 0.4
+
+	data, err := os.ReadFile(skillPath("config.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse config.json: %w", err)
+	}
+	return cfg, nil
+}
+
+// mustSkillConfig loads config.json or exits with an error.
+func mustSkillConfig() *skillConfig {
+	cfg, err := loadSkillConfig()
+	if err != nil {
+		fatal("Load config failed: %v", err)
+	}
+	return cfg
+}
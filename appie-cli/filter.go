@@ -0,0 +1,392 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	appie "github.com/gwillem/appie-go"
+)
+
+// allergenTerms maps common allergies to the ingredient words that contain
+// them. Matching errs on the side of caution: a false positive only hides a
+// recipe, a false negative can make someone ill.
+var allergenTerms = map[string][]string{
+	"gluten":       {"gluten", "tarwe", "rogge", "gerst", "spelt", "paneermeel", "tarwebloem", "pasta", "spaghetti", "penne", "macaroni", "lasagne", "tagliatelle", "fusilli", "noedels", "mie", "brood", "stokbrood", "wrap", "tortilla", "pita", "naan", "couscous", "bulgur", "bladerdeeg", "croutons", "bier", "sojasaus", "ketjap"},
+	"lactose":      {"lactose", "melk", "room", "boter", "kaas", "yoghurt", "kwark", "mascarpone", "ricotta", "mozzarella", "parmezaan", "parmigiano", "feta", "burrata", "crème fraîche", "creme fraiche", "karnemelk", "roomboter"},
+	"noten":        {"noot", "noten", "amandel", "cashew", "hazelno", "walno", "pecan", "pistache", "macadamia", "pinda"},
+	"pinda":        {"pinda", "satésaus", "satesaus"},
+	"ei":           {"ei", "eieren", "eidooier", "eiwit", "mayonaise", "eiernoedels"},
+	"vis":          {"vis", "zalm", "tonijn", "kabeljauw", "makreel", "ansjovis", "haring", "pangasius", "tilapia", "koolvis", "heek", "forel", "sardine", "vissaus", "visfilet", "vissticks"},
+	"schaaldieren": {"garnaal", "garnalen", "kreeft", "krab", "langoustine", "scampi", "gamba"},
+	"weekdieren":   {"mossel", "inktvis", "octopus", "oester", "kokkel", "sint-jakobsschelp"},
+	"soja":         {"soja", "tofu", "tempeh", "edamame", "ketjap", "miso"},
+	"selderij":     {"selderij", "knolselderij", "bleekselderij", "selderijzout"},
+	"mosterd":      {"mosterd"},
+	"sesam":        {"sesam", "tahin"},
+	"lupine":       {"lupine"},
+	"sulfiet":      {"sulfiet", "wijn", "azijn"},
+}
+
+// allergenExempt lists words that contain an allergen term but are safe.
+// Texts labelled "<term>vrij" ("glutenvrije pasta", "zuivelvrije room") are
+// exempt as a whole.
+var allergenExempt = map[string][]string{
+	"gluten":       {"currypasta", "tomatenpasta", "kruidenpasta", "knoflookpasta", "gemberpasta", "chilipasta", "sesampasta", "notenpasta", "pindapasta", "amandelpasta", "hazelnootpasta", "chocoladepasta", "misopasta", "tandpasta", "banaan"},
+	"lactose":      {"kokosmelk", "kokosroom", "havermelk", "sojamelk", "amandelmelk", "rijstmelk", "pindakaas", "notenboter", "pindaboter", "cacaoboter", "boterham", "boterbonen"},
+	"noten":        {"nootmuskaat", "kokosnoot"},
+	"schaaldieren": {"krabbetjes"},
+	"weekdieren":   {"oesterzwam"},
+	"sulfiet":      {"zwijn"},
+}
+
+// safetyAliases maps English and alternative spellings to allergenTerms keys.
+var safetyAliases = map[string]string{
+	"dairy":       "lactose",
+	"zuivel":      "lactose",
+	"milk":        "lactose",
+	"melk":        "lactose",
+	"wheat":       "gluten",
+	"tarwe":       "gluten",
+	"nuts":        "noten",
+	"nut":         "noten",
+	"noot":        "noten",
+	"notenmix":    "noten",
+	"peanut":      "pinda",
+	"peanuts":     "pinda",
+	"pindas":      "pinda",
+	"pinda's":     "pinda",
+	"egg":         "ei",
+	"eggs":        "ei",
+	"eieren":      "ei",
+	"fish":        "vis",
+	"shellfish":   "schaaldieren",
+	"crustaceans": "schaaldieren",
+	"molluscs":    "weekdieren",
+	"soy":         "soja",
+	"celery":      "selderij",
+	"mustard":     "mosterd",
+	"sesame":      "sesam",
+	"sulphite":    "sulfiet",
+	"sulfite":     "sulfiet",
+}
+
+// dislikeTranslations maps common English dislikes to Dutch terms.
+var dislikeTranslations = map[string][]string{
+	"mushrooms": {"champignon", "paddenstoel", "shiitake", "oesterzwam", "portobello"},
+	"mushroom":  {"champignon", "paddenstoel", "shiitake", "oesterzwam", "portobello"},
+	"olives":    {"olijf", "olijven"},
+	"coriander": {"koriander"},
+	"cilantro":  {"koriander"},
+	"onion":     {"ui", "uien", "sjalot"},
+	"onions":    {"ui", "uien", "sjalot"},
+	"pork":      {"varken", "spek", "ham", "chorizo"},
+	"beef":      {"rund", "biefstuk"},
+	"chicken":   {"kip"},
+	"spicy":     {"pittig", "chili", "sambal", "jalapeño", "cayenne"},
+	"eggplant":  {"aubergine"},
+	"aubergine": {"aubergine"},
+	"zucchini":  {"courgette"},
+	"beans":     {"bonen"},
+	"cheese":    {"kaas"},
+	"tomato":    {"tomaat", "tomaten"},
+	"tomatoes":  {"tomaat", "tomaten"},
+	"liver":     {"lever"},
+}
+
+// safetyRule is a single allergy or dislike from config.json.
+type safetyRule struct {
+	kind   string
+	item   string
+	key    string
+	terms  []string
+	exempt []string
+	free   []string
+}
+
+// conflict explains why an item clashes with config.json.
+type conflict struct {
+	Kind  string `json:"kind"`
+	Rule  string `json:"rule"`
+	Match string `json:"match"`
+}
+
+// describeConflicts renders conflicts as "allergy gluten (spaghetti), ...".
+func describeConflicts(conflicts []conflict) string {
+	parts := make([]string, len(conflicts))
+	for i, c := range conflicts {
+		parts[i] = fmt.Sprintf("%s %s (%s)", c.Kind, c.Rule, c.Match)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// exclusion is a product or recipe hidden by the safety filter.
+type exclusion struct {
+	ID        int        `json:"id"`
+	Title     string     `json:"title"`
+	Conflicts []conflict `json:"conflicts"`
+}
+
+// safetyFilter checks recipes and products against allergies and dislikes.
+type safetyFilter struct {
+	rules []safetyRule
+}
+
+func newSafetyFilter(cfg *skillConfig) *safetyFilter {
+	f := &safetyFilter{}
+	for _, a := range cfg.Allergies {
+		f.add("allergy", a)
+	}
+	for _, d := range cfg.Dislikes {
+		f.add("dislike", d)
+	}
+	return f
+}
+
+func (f *safetyFilter) add(kind, item string) {
+	key := strings.ToLower(strings.TrimSpace(item))
+	if key == "" {
+		return
+	}
+	if alias, ok := safetyAliases[key]; ok {
+		key = alias
+	}
+	terms := []string{key}
+	if t, ok := allergenTerms[key]; ok {
+		terms = t
+	}
+	terms = append(terms, dislikeTranslations[key]...)
+	// "lactosevrij", "melkvrij" and "zuivelvrij" all free a text of lactose.
+	free := []string{key + "vrij"}
+	for _, t := range terms {
+		free = append(free, t+"vrij")
+	}
+	for alias, k := range safetyAliases {
+		if k == key {
+			free = append(free, alias+"vrij")
+		}
+	}
+	f.rules = append(f.rules, safetyRule{kind: kind, item: item, key: key, terms: terms, exempt: allergenExempt[key], free: free})
+}
+
+func (f *safetyFilter) empty() bool {
+	return len(f.rules) == 0
+}
+
+// check returns every rule that one of the texts violates.
+func (f *safetyFilter) check(texts ...string) []conflict {
+	var conflicts []conflict
+	for _, rule := range f.rules {
+		for _, text := range texts {
+			if m := rule.find(text); m != "" {
+				conflicts = append(conflicts, conflict{Kind: rule.kind, Rule: rule.item, Match: m})
+				break
+			}
+		}
+	}
+	return conflicts
+}
+
+// find returns the offending word in text, or "" when text is safe. Two
+// letter terms ("ei", "ui") must be a whole word. Allergy terms of three
+// letters or more match anywhere, also inside compounds ("slagroomtaart",
+// "volkorenbroodje"); allergenExempt and "-vrij" labels take out the false
+// positives. Dislikes are only matched at word edges: three letter terms
+// ("kip") must start a word, longer ones start or end it ("geitenkaas",
+// "kaassaus").
+func (r safetyRule) find(text string) string {
+	text = strings.ToLower(text)
+	for _, free := range r.free {
+		if strings.Contains(text, free) {
+			return ""
+		}
+	}
+	for _, ex := range r.exempt {
+		text = strings.ReplaceAll(text, ex, " ")
+	}
+	for _, term := range r.terms {
+		var hit bool
+		switch n := len([]rune(term)); {
+		case n <= 2:
+			hit = containsWord(text, term)
+		case r.kind == "allergy":
+			hit = strings.Contains(text, term)
+		case n == 3:
+			hit = containsWordPrefix(text, term)
+		default:
+			hit = containsWordEdge(text, term)
+		}
+		if hit {
+			return term
+		}
+	}
+	return ""
+}
+
+// containsWord reports whether term is a word in s, allowing common plural
+// endings, so "ei" matches "eieren" but not "eigen".
+func containsWord(s, term string) bool {
+	for _, w := range words(s) {
+		if w == term {
+			return true
+		}
+		if rest, ok := strings.CutPrefix(w, term); ok {
+			switch rest {
+			case "s", "en", "eren", "sen", "sjes":
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// containsWordEdge reports whether a word in s starts or ends with term,
+// allowing common plural endings. Terms of several words
+// ("crème fraîche", "sint-jakobsschelp") match anywhere.
+func containsWordEdge(s, term string) bool {
+	if strings.ContainsAny(term, " -") {
+		return strings.Contains(s, term)
+	}
+	for _, w := range words(s) {
+		if strings.HasPrefix(w, term) || strings.HasSuffix(w, term) {
+			return true
+		}
+		for _, plural := range []string{"s", "en", "jes", "sjes"} {
+			if strings.HasSuffix(w, term+plural) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// words splits s into lowercase words; letters outside ASCII ("crème")
+// count as letters.
+func words(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r > 127)
+	})
+}
+
+// checkRecipe matches a recipe's title, tags and ingredients.
+func (f *safetyFilter) checkRecipe(r *recipeDetails) []conflict {
+	texts := append([]string{r.Title}, r.Tags...)
+	for _, ing := range r.Ingredients {
+		texts = append(texts, ing.words())
+	}
+	return f.check(texts...)
+}
+
+// checkProduct matches a product's title and subcategory.
+func (f *safetyFilter) checkProduct(p appie.Product) []conflict {
+	// Main categories like "Zuivel, plantaardig en eieren" are too broad to
+	// match on. Property icons are included so labels like "glutenvrij"
+	// count as exemptions.
+	return f.check(p.Title + " " + p.SubCategory + " " + strings.Join(p.PropertyIcons, " "))
+}
+
+// checkDetails matches a product's declared allergens and ingredient list
+// on top of checkProduct. "May contain" counts as well: traces are enough
+// for a reaction.
+func (f *safetyFilter) checkDetails(d *productDetails) []conflict {
+	conflicts := f.checkProduct(d.Product)
+	found := map[string]bool{}
+	for _, c := range conflicts {
+		found[c.Rule] = true
+	}
+	for _, rule := range f.rules {
+		if found[rule.item] {
+			continue
+		}
+		var match string
+		switch {
+		case rule.kind == "allergy" && slices.Contains(d.Contains, rule.key):
+			match = "bevat " + rule.key
+		case rule.kind == "allergy" && slices.Contains(d.MayContain, rule.key):
+			match = "kan " + rule.key + " bevatten"
+		default:
+			match = rule.find(d.Ingredients)
+		}
+		if match != "" {
+			conflicts = append(conflicts, conflict{Kind: rule.kind, Rule: rule.item, Match: match})
+		}
+	}
+	return conflicts
+}
+
+// allergies returns the allergy rules only, for checks that cost a detail
+// lookup per product.
+func (f *safetyFilter) allergies() *safetyFilter {
+	a := &safetyFilter{}
+	for _, r := range f.rules {
+		if r.kind == "allergy" {
+			a.rules = append(a.rules, r)
+		}
+	}
+	return a
+}
+
+// checkDeclared fetches a product's details and checks its declared
+// allergens and ingredient list against the allergy rules. A product whose
+// details cannot be loaded passes: its title was checked already.
+func (f *safetyFilter) checkDeclared(ctx context.Context, client *appie.Client, id int) []conflict {
+	allergies := f.allergies()
+	if allergies.empty() {
+		return nil
+	}
+	d, err := getProductDetails(ctx, client, id)
+	if err != nil {
+		return nil
+	}
+	return allergies.checkDetails(d)
+}
+
+// filterDeclared removes products that declare an allergen from
+// config.json, which titles rarely mention (cashews in pesto).
+func (f *safetyFilter) filterDeclared(ctx context.Context, client *appie.Client, products []appie.Product) ([]appie.Product, []exclusion) {
+	if !f.hasAllergies() {
+		return products, nil
+	}
+	conflicts := make([][]conflict, len(products))
+	parallel(len(products), 8, func(i int) {
+		conflicts[i] = f.checkDeclared(ctx, client, products[i].ID)
+	})
+	kept := make([]appie.Product, 0, len(products))
+	var excluded []exclusion
+	for i, p := range products {
+		if len(conflicts[i]) > 0 {
+			excluded = append(excluded, exclusion{ID: p.ID, Title: p.Title, Conflicts: conflicts[i]})
+			continue
+		}
+		kept = append(kept, p)
+	}
+	return kept, excluded
+}
+
+// hasAllergies reports whether any rule is an allergy rather than a dislike.
+func (f *safetyFilter) hasAllergies() bool {
+	for _, r := range f.rules {
+		if r.kind == "allergy" {
+			return true
+		}
+	}
+	return false
+}
+
+// filterProducts removes unsafe products.
+func (f *safetyFilter) filterProducts(products []appie.Product) ([]appie.Product, []exclusion) {
+	if f.empty() {
+		return products, nil
+	}
+	kept := make([]appie.Product, 0, len(products))
+	var excluded []exclusion
+	for _, p := range products {
+		if c := f.checkProduct(p); len(c) > 0 {
+			excluded = append(excluded, exclusion{ID: p.ID, Title: p.Title, Conflicts: c})
+			continue
+		}
+		kept = append(kept, p)
+	}
+	return kept, excluded
+}
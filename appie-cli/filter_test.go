@@ -0,0 +1,97 @@
+package main
+
+import (
+	"testing"
+
+	appie "github.com/gwillem/appie-go"
+)
+
+func TestSafetyFilterCheck(t *testing.T) {
+	f := newSafetyFilter(&skillConfig{
+		Allergies: []string{"gluten", "zuivel", "ei", "noten", "vis"},
+		Dislikes:  []string{"mushrooms", "koriander"},
+	})
+	tests := []struct {
+		text string
+		want string // the rule that fires, "" for safe
+	}{
+		{"spaghetti", "gluten"},
+		{"volkorenpasta", "gluten"},
+		{"pastasaus", "gluten"},
+		{"rode currypasta", ""},
+		{"glutenvrije pasta", ""},
+		{"geitenkaas", "zuivel"},
+		{"kaassaus", "zuivel"},
+		{"slagroom", "zuivel"},
+		{"boterhamworst", ""},
+		{"zuivelvrije room", ""},
+		{"melkvrije chocolade", ""},
+		{"lactosevrije melk", ""},
+		{"kokosmelk", ""},
+		{"slagroomtaart", "zuivel"},
+		{"plantaardige room", "zuivel"},
+		{"volkorenbroodje", "gluten"},
+		{"chocoladehazelnootpasta", "noten"},
+		{"banaan", ""},
+		{"zeevis", "vis"},
+		{"2 eieren", "ei"},
+		{"eigen saus", ""},
+		{"hazelnoten", "noten"},
+		{"nootmuskaat", ""},
+		{"vissaus", "vis"},
+		{"kastanjechampignons", "mushrooms"},
+		{"verse koriander", "koriander"},
+		{"rijst", ""},
+	}
+	for _, tt := range tests {
+		var got string
+		if c := f.check(tt.text); len(c) > 0 {
+			got = c[0].Rule
+		}
+		if got != tt.want {
+			t.Errorf("check(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCheckDetails(t *testing.T) {
+	f := newSafetyFilter(&skillConfig{Allergies: []string{"noten", "selderij"}, Dislikes: []string{"koriander"}})
+	d := &productDetails{
+		Product:     appie.Product{Title: "AH Pesto verde"},
+		Contains:    []string{"noten"},
+		MayContain:  []string{"selderij"},
+		Ingredients: "basilicum, zonnebloemolie, cashewnoten, koriander",
+	}
+	want := map[string]string{"noten": "bevat noten", "selderij": "kan selderij bevatten", "koriander": "koriander"}
+	got := f.checkDetails(d)
+	if len(got) != len(want) {
+		t.Fatalf("checkDetails = %+v, want %d conflicts", got, len(want))
+	}
+	for _, c := range got {
+		if want[c.Rule] != c.Match {
+			t.Errorf("conflict %s matched %q, want %q", c.Rule, c.Match, want[c.Rule])
+		}
+	}
+}
+
+func TestContainsWordEdge(t *testing.T) {
+	tests := []struct {
+		s, term string
+		want    bool
+	}{
+		{"geitenkaas", "kaas", true},
+		{"kaasjes", "kaas", true},
+		{"oude kaas", "kaas", true},
+		{"currypasta", "pasta", true},
+		{"pastasaus", "pasta", true},
+		{"boterhamworst", "boter", true},
+		{"verboterd", "boter", false},
+		{"crème fraîche", "crème fraîche", true},
+		{"gepelde sint-jakobsschelpen", "sint-jakobsschelp", true},
+	}
+	for _, tt := range tests {
+		if got := containsWordEdge(tt.s, tt.term); got != tt.want {
+			t.Errorf("containsWordEdge(%q, %q) = %v, want %v", tt.s, tt.term, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// productFrequency is how often a product was bought: the days it appears on
+// a receipt or was put on the list, oldest first, with the quantity bought.
+// Days within sameShopDays of each other are one shop (listed Monday,
+// delivered Wednesday).
+type productFrequency struct {
+	ProductID int           `json:"productId,omitempty"`
+	Title     string        `json:"title"`
+	Days      []purchaseDay `json:"-"`
+}
+
+type purchaseDay struct {
+	Date time.Time
+	Qty  int
+}
+
+// purchaseFrequencies collects purchase days per product from receipts and
+// list additions in purchase-history.json. Lines are keyed by product ID;
+// receipt lines without one are matched to a list addition by title, or
+// else kept under their description. Bonus discount lines are skipped.
+func purchaseFrequencies(h *purchaseHistory) map[string]*productFrequency {
+	byTitle := map[string]int{}
+	for _, a := range h.ListAdditions {
+		byTitle[strings.ToLower(a.Title)] = a.ProductID
+	}
+	out := map[string]*productFrequency{}
+	days := map[string]map[string]int{}
+	add := func(id int, title, date string, qty int) {
+		if id == 0 {
+			id = byTitle[strings.ToLower(title)]
+		}
+		day := receiptDay(date)
+		t, err := time.Parse(dateLayout, day)
+		if err != nil {
+			return
+		}
+		key := frequencyKey(id, title)
+		f, ok := out[key]
+		if !ok {
+			f = &productFrequency{ProductID: id, Title: title}
+			out[key], days[key] = f, map[string]int{}
+		}
+		qty = max(qty, 1)
+		if i, ok := days[key][day]; ok {
+			// The list addition and the receipt of one shop.
+			f.Days[i].Qty = max(f.Days[i].Qty, qty)
+			return
+		}
+		days[key][day] = len(f.Days)
+		f.Days = append(f.Days, purchaseDay{t, qty})
+	}
+	for _, a := range h.ListAdditions {
+		add(a.ProductID, a.Title, a.Date, a.Quantity)
+	}
+	for _, r := range h.Receipts {
+		for _, it := range r.Items {
+			if it.Amount < 0 || it.Description == "" {
+				continue
+			}
+			add(it.ProductID, it.Description, r.Date, it.Quantity)
+		}
+	}
+	for _, f := range out {
+		sort.Slice(f.Days, func(i, j int) bool { return f.Days[i].Date.Before(f.Days[j].Date) })
+		shops := f.Days[:0]
+		for _, d := range f.Days {
+			if n := len(shops); n > 0 && d.Date.Sub(shops[n-1].Date).Hours()/24 <= sameShopDays {
+				shops[n-1].Qty = max(shops[n-1].Qty, d.Qty)
+				continue
+			}
+			shops = append(shops, d)
+		}
+		f.Days = shops
+	}
+	return out
+}
+
+const sameShopDays = 3
+
+func frequencyKey(id int, title string) string {
+	if id > 0 {
+		return fmt.Sprintf("id:%d", id)
+	}
+	return "title:" + strings.ToLower(title)
+}
+
+// gaps returns the days between consecutive purchases.
+func (f *productFrequency) gaps() []float64 {
+	var gaps []float64
+	for i := 1; i < len(f.Days); i++ {
+		gaps = append(gaps, f.Days[i].Date.Sub(f.Days[i-1].Date).Hours()/24)
+	}
+	return gaps
+}
+
+// interval is the median number of days between purchases; ok is false
+// when the product was bought fewer than twice.
+func (f *productFrequency) interval() (days float64, ok bool) {
+	gaps := f.gaps()
+	if len(gaps) == 0 {
+		return 0, false
+	}
+	return median(gaps), true
+}
+
+// median sorts values and returns the middle one.
+func median(values []float64) float64 {
+	sort.Float64s(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
+
+// last is the most recent purchase day.
+func (f *productFrequency) last() time.Time {
+	return f.Days[len(f.Days)-1].Date
+}
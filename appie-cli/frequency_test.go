@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	appie "github.com/gwillem/appie-go"
+)
+
+func TestPurchaseFrequencies(t *testing.T) {
+	h := &purchaseHistory{
+		ListAdditions: []listAddition{
+			{Date: "2026-03-01", ProductID: 1, Title: "AH Halfvolle melk", Quantity: 2},
+			{Date: "2026-03-08", ProductID: 1, Title: "AH Halfvolle melk", Quantity: 1},
+		},
+		Receipts: []storedReceipt{
+			{receipt: receipt{Receipt: appie.Receipt{Date: "2026-03-03T14:32:00"}, Items: []receiptItem{
+				// Delivered two days after listing: the same shop.
+				{ReceiptItem: appie.ReceiptItem{Description: "AH halfvolle melk", Quantity: 3, Amount: 3.87}},
+				{ReceiptItem: appie.ReceiptItem{Description: "AH Volkoren brood", Quantity: 1, Amount: 2.19}},
+				{ReceiptItem: appie.ReceiptItem{Description: "BONUS AH BROOD", Amount: -0.50}},
+			}}},
+			{receipt: receipt{Receipt: appie.Receipt{Date: "2026-03-15"}, Items: []receiptItem{
+				{ReceiptItem: appie.ReceiptItem{Description: "AH Volkoren brood", ProductID: 5, Quantity: 2, Amount: 4.38}},
+			}}},
+		},
+	}
+	tests := []struct {
+		key  string
+		days []string
+		qty  []int
+	}{
+		{"id:1", []string{"2026-03-01", "2026-03-08"}, []int{3, 1}},
+		{"title:ah volkoren brood", []string{"2026-03-03"}, []int{1}},
+		{"id:5", []string{"2026-03-15"}, []int{2}},
+	}
+	freqs := purchaseFrequencies(h)
+	if len(freqs) != len(tests) {
+		t.Errorf("got %d products, want %d", len(freqs), len(tests))
+	}
+	for _, tt := range tests {
+		f, ok := freqs[tt.key]
+		if !ok {
+			t.Errorf("%s missing", tt.key)
+			continue
+		}
+		if len(f.Days) != len(tt.days) {
+			t.Errorf("%s: %d days, want %d", tt.key, len(f.Days), len(tt.days))
+			continue
+		}
+		for i, d := range f.Days {
+			if got := d.Date.Format(dateLayout); got != tt.days[i] || d.Qty != tt.qty[i] {
+				t.Errorf("%s: day %d = %s x%d, want %s x%d", tt.key, i, got, d.Qty, tt.days[i], tt.qty[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// runGQL runs an ad-hoc GraphQL query read from a file or stdin and prints
+// the full response, errors included.
+func runGQL(ctx context.Context, configPath string, argv []string) {
+	args := parseArgs(argv, "anon")
+	var query []byte
+	var err error
+	if file := args.arg(0); file != "" && file != "-" {
+		query, err = os.ReadFile(file)
+	} else {
+		query, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		fatal("Read query failed: %v", err)
+	}
+	if len(bytes.TrimSpace(query)) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: appie-cli gql [query.graphql|-] [--variables '{\"id\": 123}'] [--anon]")
+		os.Exit(1)
+	}
+
+	var variables map[string]any
+	if v := args.str("variables", ""); v != "" {
+		if err := json.Unmarshal([]byte(v), &variables); err != nil {
+			fatal("--variables must be a JSON object: %v", err)
+		}
+	}
+
+	client := mustAuth
+	if args.flag("anon", false) {
+		client = mustAnon
+	}
+	body, err := graphqlQuery(ctx, client(ctx, configPath), string(query), variables)
+	if err != nil {
+		fatal("GraphQL request failed: %v", err)
+	}
+	var out any
+	if err := json.Unmarshal(body, &out); err != nil {
+		fatal("parse error: %v", err)
+	}
+	printJSON(out)
+}
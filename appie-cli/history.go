@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func runHistory(ctx context.Context, configPath string, argv []string) {
+	if len(argv) < 1 {
+		historyUsage()
+	}
+	args := parseArgs(argv[1:])
+	h, err := loadMealHistory()
+	if err != nil {
+		fatal("Load history failed: %v", err)
+	}
+
+	switch argv[0] {
+	case "approve":
+		id := args.argInt(0, 0)
+		if id <= 0 {
+			historyUsage()
+		}
+		date := args.str("date", time.Now().Format(dateLayout))
+		products, err := historyProducts(args)
+		if err != nil {
+			fatal("%v", err)
+		}
+		recipe, err := getRecipeDetails(ctx, mustAnon(ctx, configPath), id)
+		if err != nil {
+			fatal("Get recipe failed: %v", err)
+		}
+		if plan := args.str("from-plan", ""); plan != "" {
+			if err := productsFromPlan(plan, recipe.Title, products); err != nil {
+				fatal("Read plan failed: %v", err)
+			}
+		}
+		entry := approvedMeal{
+			Date:        date,
+			RecipeName:  recipe.Title,
+			RecipeID:    recipe.ID,
+			CookingTime: recipe.CookTime,
+			ProductIDs:  products,
+			Notes:       args.str("notes", ""),
+			Tags:        recipe.Tags,
+		}
+		for _, ing := range recipe.Ingredients {
+			entry.Ingredients = append(entry.Ingredients, ing.name())
+		}
+		addHistory(h, &mealHistory{Approved: []approvedMeal{entry}})
+		printJSON(entry)
+
+	case "reject":
+		id := args.argInt(0, 0)
+		reason := args.str("reason", "")
+		if id <= 0 || reason == "" {
+			historyUsage()
+		}
+		recipe, err := getRecipeDetails(ctx, mustAnon(ctx, configPath), id)
+		if err != nil {
+			fatal("Get recipe failed: %v", err)
+		}
+		entry := rejectedMeal{
+			Date:       args.str("date", time.Now().Format(dateLayout)),
+			RecipeName: recipe.Title,
+			RecipeID:   recipe.ID,
+			Reason:     reason,
+			Tags:       recipe.Tags,
+		}
+		addHistory(h, &mealHistory{Rejected: []rejectedMeal{entry}})
+		printJSON(entry)
+
+	case "feedback":
+		text := strings.Join(args.pos, " ")
+		if text == "" {
+			historyUsage()
+		}
+		entry := mealFeedback{
+			Date:     args.str("date", time.Now().Format(dateLayout)),
+			RecipeID: args.num("recipe", 0),
+			Text:     text,
+		}
+		if entry.RecipeID > 0 {
+			recipe, err := getRecipeDetails(ctx, mustAnon(ctx, configPath), entry.RecipeID)
+			if err != nil {
+				fatal("Get recipe failed: %v", err)
+			}
+			entry.RecipeName = recipe.Title
+		}
+		addHistory(h, &mealHistory{Feedback: []mealFeedback{entry}})
+		printJSON(entry)
+
+	case "list":
+		since := time.Time{}
+		if v := args.str("since", ""); v != "" {
+			since, err = parseSince(v, time.Now())
+			if err != nil {
+				fatal("%v", err)
+			}
+		}
+		printJSON(h.since(since, args.str("type", "")))
+
+	default:
+		historyUsage()
+	}
+}
+
+func historyUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: appie-cli history approve <recipe-id> [--date YYYY-MM-DD] [--notes \"...\"]")
+	fmt.Fprintln(os.Stderr, "                                           [--products name=id,...] [--from-plan proposal.json]")
+	fmt.Fprintln(os.Stderr, "       appie-cli history reject <recipe-id> --reason \"...\" [--date YYYY-MM-DD]")
+	fmt.Fprintln(os.Stderr, "       appie-cli history feedback \"text\" [--recipe <id>] [--date YYYY-MM-DD]")
+	fmt.Fprintln(os.Stderr, "       appie-cli history list [--since YYYY-MM-DD|4w] [--type approved|rejected|feedback]")
+	os.Exit(1)
+}
+
+// addHistory validates the new entries in add, appends them to h and writes
+// meal-history.json. Problems in older, hand-edited entries are only reported.
+func addHistory(h, add *mealHistory) {
+	if issues := add.validate(); len(issues) > 0 {
+		fatal("Invalid entry: %s", strings.Join(issues, "; "))
+	}
+	for _, issue := range h.validate() {
+		fmt.Fprintf(os.Stderr, "warning: meal-history.json %s\n", issue)
+	}
+	h.Approved = append(h.Approved, add.Approved...)
+	h.Rejected = append(h.Rejected, add.Rejected...)
+	h.Feedback = append(h.Feedback, add.Feedback...)
+	if err := h.save(); err != nil {
+		fatal("Save history failed: %v", err)
+	}
+}
+
+// historyProducts parses --products kipfilet=123,ui=456.
+func historyProducts(args cmdArgs) (map[string]int, error) {
+	products := map[string]int{}
+	for _, pair := range args.list("products") {
+		name, id, ok := strings.Cut(pair, "=")
+		n, err := strconv.Atoi(strings.TrimSpace(id))
+		if !ok || err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid --products entry %q (want name=id)", pair)
+		}
+		products[strings.TrimSpace(name)] = n
+	}
+	return products, nil
+}
+
+// productsFromPlan adds the products that plan-week put on the list for a
+// recipe, keyed by ingredient name.
+func productsFromPlan(path, title string, products map[string]int) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var plan weekPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return err
+	}
+	for _, item := range plan.Shopping {
+		if item.ID == 0 {
+			continue
+		}
+		for i, meal := range item.For {
+			if meal == title && i < len(item.Ingredients) {
+				products[item.Ingredients[i]] = item.ID
+			}
+		}
+	}
+	return nil
+}
+
+// historyEntry is one row of history list output.
+type historyEntry struct {
+	Type  string `json:"type"`
+	Date  string `json:"date"`
+	Entry any    `json:"entry"`
+}
+
+// since returns entries dated on or after since, newest first. kind limits
+// the output to approved, rejected or feedback entries.
+func (h *mealHistory) since(since time.Time, kind string) map[string]any {
+	entries := []historyEntry{}
+	add := func(t, date string, e any) {
+		if kind != "" && kind != t {
+			return
+		}
+		if d, err := time.Parse(dateLayout, date); err == nil && d.Before(since) {
+			return
+		}
+		entries = append(entries, historyEntry{Type: t, Date: date, Entry: e})
+	}
+	for _, m := range h.Approved {
+		add("approved", m.Date, m)
+	}
+	for _, m := range h.Rejected {
+		add("rejected", m.Date, m)
+	}
+	for _, f := range h.Feedback {
+		add("feedback", f.Date, f)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Date > entries[j].Date })
+
+	out := map[string]any{"entries": entries}
+	if issues := h.validate(); len(issues) > 0 {
+		out["issues"] = issues
+	}
+	return out
+}
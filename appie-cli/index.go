@@ -0,0 +1,460 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	appie "github.com/gwillem/appie-go"
+	"github.com/markooms/openclaw-skill-albert-heijn/appie-cli/parser"
+)
+
+// indexedProduct is a product in the local index, with where and when it
+// was last seen. Prices are as of Seen.
+type indexedProduct struct {
+	appie.Product
+	Source string `json:"source"`
+	Seen   string `json:"seen"`
+}
+
+// productIndex is product-index.json: every product seen via search,
+// product, bonus-products and previously-bought, and an inverted index from
+// stemmed title, brand and category terms to product IDs.
+type productIndex struct {
+	Built    string                 `json:"built,omitempty"`
+	Products map[int]indexedProduct `json:"products"`
+	Terms    map[string][]int       `json:"terms"`
+}
+
+func loadProductIndex() (*productIndex, error) {
+	idx := &productIndex{}
+	data, err := os.ReadFile(skillPath("product-index.json"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, idx); err != nil {
+			return nil, fmt.Errorf("parse product-index.json: %w", err)
+		}
+	}
+	if idx.Products == nil {
+		idx.Products = map[int]indexedProduct{}
+	}
+	if len(idx.Terms) == 0 && len(idx.Products) > 0 {
+		idx.reindex() // edited by hand
+	}
+	return idx, nil
+}
+
+// save rebuilds the terms and writes product-index.json.
+func (idx *productIndex) save() error {
+	idx.reindex()
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(skillPath("product-index.json"), append(data, '\n'), 0o644)
+}
+
+// add stores products seen via source, replacing older copies, and reports
+// whether the index changed. Products without a title (e.g. a failed lookup)
+// are skipped, and so are products without a price (previously-bought) that
+// the index already has priced.
+func (idx *productIndex) add(source string, products ...appie.Product) bool {
+	today := time.Now().Format(dateLayout)
+	changed := false
+	for _, p := range products {
+		if p.ID == 0 || p.Title == "" {
+			continue
+		}
+		old, ok := idx.Products[p.ID]
+		if ok && p.Price.Now == 0 && old.Price.Now > 0 {
+			continue
+		}
+		p.Images = nil // the bulk of a product, and not searchable
+		ip := indexedProduct{Product: p, Source: source, Seen: today}
+		if ok && reflect.DeepEqual(old, ip) {
+			continue
+		}
+		idx.Products[p.ID] = ip
+		changed = true
+	}
+	return changed
+}
+
+func (idx *productIndex) reindex() {
+	idx.Terms = map[string][]int{}
+	ids := make([]int, 0, len(idx.Products))
+	for id := range idx.Products {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		p := idx.Products[id]
+		seen := map[string]bool{}
+		for _, t := range indexTerms(p.Title + " " + p.Brand + " " + p.Category + " " + p.SubCategory) {
+			if !seen[t] {
+				seen[t] = true
+				idx.Terms[t] = append(idx.Terms[t], id)
+			}
+		}
+	}
+}
+
+type seenProducts struct {
+	source   string
+	products []appie.Product
+}
+
+// pendingSeen holds the products seen by this command until flushSeen adds
+// them to the index.
+var pendingSeen []seenProducts
+
+// recordSeen queues products for the index; flushSeen writes them.
+func recordSeen(source string, products ...appie.Product) {
+	if len(products) > 0 {
+		pendingSeen = append(pendingSeen, seenProducts{source, products})
+	}
+}
+
+// flushSeen adds the products seen by this command to product-index.json,
+// and only rewrites it when something changed. main calls it once the
+// command is done. Like flushAdditions it only warns on failure.
+func flushSeen() {
+	if len(pendingSeen) == 0 {
+		return
+	}
+	idx, err := loadProductIndex()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		return
+	}
+	changed := false
+	for _, s := range pendingSeen {
+		if idx.add(s.source, s.products...) {
+			changed = true
+		}
+	}
+	pendingSeen = nil
+	if !changed {
+		return
+	}
+	if err := idx.save(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: save product-index.json: %v\n", err)
+	}
+}
+
+var foldAccents = strings.NewReplacer(
+	"á", "a", "à", "a", "ä", "a", "â", "a",
+	"é", "e", "è", "e", "ë", "e", "ê", "e",
+	"í", "i", "ï", "i", "î", "i",
+	"ó", "o", "ö", "o", "ô", "o",
+	"ú", "u", "ü", "u", "û", "u",
+	"ç", "c", "ñ", "n",
+)
+
+// indexTerms splits text into lowercase, accent-free, stemmed terms.
+func indexTerms(text string) []string {
+	text = foldAccents.Replace(strings.ToLower(text))
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+	var terms []string
+	for _, w := range words {
+		if w = stemDutch(strings.Trim(w, "'")); len(w) >= 2 {
+			terms = append(terms, w)
+		}
+	}
+	return terms
+}
+
+// stemDutch reduces a word to its singular without diminutive:
+// "tomaatjes", "tomaten" and "tomaat" all become "tomaat".
+func stemDutch(w string) string {
+	w = parser.Singular(w)
+	switch {
+	case len(w) > 6 && strings.HasSuffix(w, "etje"):
+		// balletje -> bal
+		w = strings.TrimSuffix(w, "etje")
+		if n := len(w); w[n-1] == w[n-2] {
+			w = w[:n-1]
+		}
+	case len(w) > 5 && strings.HasSuffix(w, "pje"):
+		w = strings.TrimSuffix(w, "pje") // boompje -> boom
+	case len(w) > 4 && strings.HasSuffix(w, "je"):
+		w = strings.TrimSuffix(w, "je") // tomaatje -> tomaat
+	}
+	return w
+}
+
+// trigrams returns the padded character trigrams of a term.
+func trigrams(term string) map[string]bool {
+	r := []rune(" " + term + " ")
+	out := map[string]bool{}
+	for i := 0; i+3 <= len(r); i++ {
+		out[string(r[i:i+3])] = true
+	}
+	return out
+}
+
+// termMatch scores how well index term t matches query term q: 1 for the
+// same term, 0.8 when one contains the other (compounds like "kipfilet" for
+// "filet"), else the trigram similarity scaled to at most 0.7, or 0 below
+// the fuzzy threshold.
+func termMatch(q, t string) float64 {
+	switch {
+	case q == t:
+		return 1
+	case len(q) >= 3 && strings.Contains(t, q), len(t) >= 3 && strings.Contains(q, t):
+		return 0.8
+	}
+	a, b := trigrams(q), trigrams(t)
+	shared := 0
+	for g := range a {
+		if b[g] {
+			shared++
+		}
+	}
+	dice := 2 * float64(shared) / float64(len(a)+len(b))
+	if dice < 0.5 {
+		return 0
+	}
+	return 0.7 * dice
+}
+
+// offlineFilter narrows offline search results.
+type offlineFilter struct {
+	Brand    string
+	Category string
+	MinPrice float64
+	MaxPrice float64
+	Bonus    bool
+}
+
+func (f offlineFilter) match(p indexedProduct) bool {
+	contains := func(s, sub string) bool { return strings.Contains(strings.ToLower(s), strings.ToLower(sub)) }
+	switch {
+	case f.Brand != "" && !contains(p.Brand, f.Brand):
+		return false
+	case f.Category != "" && !contains(p.Category, f.Category) && !contains(p.SubCategory, f.Category):
+		return false
+	case f.MinPrice > 0 && p.Price.Now < f.MinPrice:
+		return false
+	case f.MaxPrice > 0 && p.Price.Now > f.MaxPrice:
+		return false
+	case f.Bonus && !p.IsBonus:
+		return false
+	}
+	return true
+}
+
+// search returns the products matching every query term, best match first.
+// Each query term counts with its best matching index term; bonus products
+// win ties.
+func (idx *productIndex) search(query string, f offlineFilter) []indexedProduct {
+	qterms := indexTerms(query)
+	if len(qterms) == 0 {
+		return nil
+	}
+	scores := map[int]float64{}
+	for i, q := range qterms {
+		best := map[int]float64{}
+		for t, ids := range idx.Terms {
+			s := termMatch(q, t)
+			if s == 0 {
+				continue
+			}
+			for _, id := range ids {
+				best[id] = max(best[id], s)
+			}
+		}
+		next := map[int]float64{}
+		for id, s := range best {
+			if prev, ok := scores[id]; ok || i == 0 {
+				next[id] = prev + s
+			}
+		}
+		scores = next
+	}
+
+	var out []indexedProduct
+	for id := range scores {
+		if p := idx.Products[id]; f.match(p) {
+			out = append(out, p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if scores[a.ID] != scores[b.ID] {
+			return scores[a.ID] > scores[b.ID]
+		}
+		if a.IsBonus != b.IsBonus {
+			return a.IsBonus
+		}
+		return a.Title < b.Title
+	})
+	return out
+}
+
+// productFromBought converts a previously-bought entry, which has no price.
+func productFromBought(b boughtProduct) appie.Product {
+	return appie.Product{ID: b.ID, Title: b.Title, Brand: b.Brand, Category: b.Category, IsPreviouslyBought: true}
+}
+
+// product converts a bonus-products entry.
+func (p bonusProduct) product() appie.Product {
+	return appie.Product{
+		ID:             p.ID,
+		Title:          p.Title,
+		Brand:          p.Brand,
+		Category:       p.Category,
+		SubCategory:    p.SubCategory,
+		UnitSize:       p.UnitSize,
+		Price:          appie.Price{Now: p.CurrentPrice, Was: p.PriceBeforeBonus},
+		IsBonus:        true,
+		BonusMechanism: p.BonusMechanism,
+		IsAvailable:    true,
+	}
+}
+
+// runIndexBuild refreshes the index from the bonus products and the full
+// purchase history, on top of what search and product already recorded.
+// Previously bought products are looked up for their current price.
+func runIndexBuild(ctx context.Context, configPath string, args cmdArgs) {
+	client := mustAuth(ctx, configPath)
+	idx, err := loadProductIndex()
+	if err != nil {
+		fatal("Load product index failed: %v", err)
+	}
+	before := len(idx.Products)
+	added := map[string]int{}
+
+	if args.flag("bonus", true) {
+		bonus, err := getBonusProductsTyped(ctx, client, args.num("bonus-size", 500))
+		if err != nil {
+			fatal("Get bonus products failed: %v", err)
+		}
+		for _, b := range bonus {
+			idx.add("bonus-products", b.product())
+		}
+		added["bonus-products"] = len(bonus)
+	}
+	if args.flag("bought", true) {
+		bought, err := getAllPreviouslyBought(ctx, client)
+		if err != nil {
+			fatal("Get previously bought failed: %v", err)
+		}
+		ids := make([]int, len(bought))
+		for i, b := range bought {
+			ids[i] = b.ID
+			idx.add("previously-bought", productFromBought(b))
+		}
+		for start := 0; start < len(ids); start += 50 {
+			products, err := client.GetProductsByIDs(ctx, ids[start:min(start+50, len(ids))])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "warning: prices for previously bought products: %v\n", err)
+				break
+			}
+			for i := range products {
+				products[i].IsPreviouslyBought = true
+			}
+			idx.add("previously-bought", products...)
+		}
+		added["previously-bought"] = len(bought)
+	}
+
+	idx.Built = time.Now().Format(time.RFC3339)
+	if err := idx.save(); err != nil {
+		fatal("Save product index failed: %v", err)
+	}
+	printJSON(map[string]any{
+		"ok":       true,
+		"products": len(idx.Products),
+		"new":      len(idx.Products) - before,
+		"terms":    len(idx.Terms),
+		"fetched":  added,
+	})
+}
+
+func runIndex(ctx context.Context, configPath string, argv []string) {
+	args := parseArgs(argv, "bonus", "bought")
+	switch args.arg(0) {
+	case "build":
+		runIndexBuild(ctx, configPath, args)
+	case "stats":
+		idx, err := loadProductIndex()
+		if err != nil {
+			fatal("Load product index failed: %v", err)
+		}
+		sources := map[string]int{}
+		for _, p := range idx.Products {
+			sources[p.Source]++
+		}
+		printJSON(map[string]any{
+			"built":    idx.Built,
+			"products": len(idx.Products),
+			"terms":    len(idx.Terms),
+			"sources":  sources,
+		})
+	default:
+		fmt.Fprintln(os.Stderr, "Usage: appie-cli index build [--no-bonus] [--no-bought] [--bonus-size n]")
+		fmt.Fprintln(os.Stderr, "       appie-cli index stats")
+		os.Exit(1)
+	}
+}
+
+// runOfflineSearch answers search from product-index.json.
+func runOfflineSearch(args cmdArgs) {
+	idx, err := loadProductIndex()
+	if err != nil {
+		fatal("Load product index failed: %v", err)
+	}
+	if len(idx.Products) == 0 {
+		fatal("Product index is empty; run appie-cli index build first")
+	}
+	f := offlineFilter{
+		Brand:    args.str("brand", ""),
+		Category: args.str("category", ""),
+		Bonus:    args.flag("bonus", false),
+	}
+	for name, dst := range map[string]*float64{"min-price": &f.MinPrice, "max-price": &f.MaxPrice} {
+		if v := args.str(name, ""); v != "" {
+			if _, err := fmt.Sscanf(strings.ReplaceAll(v, ",", "."), "%g", dst); err != nil {
+				fatal("Invalid --%s %q", name, v)
+			}
+		}
+	}
+
+	hits := idx.search(args.arg(0), f)
+	filter := newSafetyFilter(mustSkillConfig())
+	var excluded []exclusion
+	if args.flag("safe", true) && !filter.empty() {
+		kept := hits[:0]
+		for _, p := range hits {
+			if c := filter.checkProduct(p.Product); len(c) > 0 {
+				excluded = append(excluded, exclusion{ID: p.ID, Title: p.Title, Conflicts: c})
+				continue
+			}
+			kept = append(kept, p)
+		}
+		hits = kept
+	}
+	if n := args.argInt(1, 10); len(hits) > n {
+		hits = hits[:n]
+	}
+	if hits == nil {
+		hits = []indexedProduct{}
+	}
+	result := map[string]any{"products": hits, "offline": true, "built": idx.Built}
+	if excluded != nil {
+		result["excluded"] = excluded
+	}
+	printJSON(result)
+}
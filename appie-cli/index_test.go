@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	appie "github.com/gwillem/appie-go"
+)
+
+func TestProductIndexAdd(t *testing.T) {
+	idx := &productIndex{Products: map[int]indexedProduct{}}
+	melk := appie.Product{ID: 1, Title: "AH Halfvolle melk", Price: appie.Price{Now: 1.29}}
+	tests := []struct {
+		name    string
+		source  string
+		product appie.Product
+		changed bool
+	}{
+		{"new product", "search", melk, true},
+		{"seen again", "search", melk, false},
+		{"other source", "product", melk, true},
+		{"new price", "product", appie.Product{ID: 1, Title: "AH Halfvolle melk", Price: appie.Price{Now: 0.99}}, true},
+		{"unpriced copy", "previously-bought", appie.Product{ID: 1, Title: "AH Halfvolle melk"}, false},
+		{"failed lookup", "product", appie.Product{ID: 2}, false},
+	}
+	for _, tt := range tests {
+		if got := idx.add(tt.source, tt.product); got != tt.changed {
+			t.Errorf("%s: add = %v, want %v", tt.name, got, tt.changed)
+		}
+	}
+	if p := idx.Products[1]; p.Price.Now != 0.99 || p.Source != "product" {
+		t.Errorf("indexed %+v, want the 0.99 copy from product", p)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -109,40 +110,77 @@ func main() {
 		fmt.Println(`{"ok": true, "message": "Login successful"}`)
 
 	case "member":
+		args := parseArgs(os.Args[2:], "redact")
 		client := mustAuth(ctx, configPath)
+		if args.arg(0) == "insights" { // same as member-profile
+			profile, err := getMemberProfile(ctx, client)
+			if err != nil {
+				fatal("Get member profile failed: %v", err)
+			}
+			printJSON(profile)
+			return
+		}
 		member, err := client.GetMember(ctx)
 		if err != nil {
 			fatal("Get member failed: %v", err)
 		}
-		printJSON(member)
+		printRedacted(args, memberFields, member)
+
+	case "member-profile":
+		client := mustAuth(ctx, configPath)
+		profile, err := getMemberProfile(ctx, client)
+		if err != nil {
+			fatal("Get member profile failed: %v", err)
+		}
+		printJSON(profile)
 
 	case "search":
-		if len(os.Args) < 3 {
-			fmt.Fprintln(os.Stderr, "Usage: appie-cli search <query> [limit]")
+		args := parseArgs(os.Args[2:], "safe", "offline", "bonus")
+		if len(args.pos) < 1 {
+			fmt.Fprintln(os.Stderr, "Usage: appie-cli search <query> [limit] [--no-safe]")
+			fmt.Fprintln(os.Stderr, "       appie-cli search <query> [limit] --offline [--brand b] [--category c] [--min-price n] [--max-price n] [--bonus]")
 			os.Exit(1)
 		}
-		client := mustAnon(ctx, configPath)
-		limit := 10
-		if len(os.Args) >= 4 {
-			limit, _ = strconv.Atoi(os.Args[3])
+		if args.flag("offline", false) {
+			runOfflineSearch(args)
+			break
 		}
-		products, err := client.SearchProducts(ctx, os.Args[2], limit)
+		client := mustAnon(ctx, configPath)
+		limit := args.argInt(1, 10)
+		products, err := client.SearchProducts(ctx, args.arg(0), limit)
 		if err != nil {
 			fatal("Search failed: %v", err)
 		}
-		printJSON(products)
+		recordSeen("search", products...)
+		filter := newSafetyFilter(mustSkillConfig())
+		if !args.flag("safe", true) || filter.empty() {
+			printJSON(products)
+			break
+		}
+		kept, excluded := filter.filterProducts(products)
+		kept, declared := filter.filterDeclared(ctx, client, kept)
+		printJSON(map[string]any{
+			"products": kept,
+			"excluded": append(excluded, declared...),
+		})
 
 	case "product":
-		if len(os.Args) < 3 {
-			fmt.Fprintln(os.Stderr, "Usage: appie-cli product <id>")
+		args := parseArgs(os.Args[2:], "details")
+		if len(args.pos) < 1 {
+			fmt.Fprintln(os.Stderr, "Usage: appie-cli product <id> [--details]")
 			os.Exit(1)
 		}
 		client := mustAnon(ctx, configPath)
-		id, _ := strconv.Atoi(os.Args[2])
+		id := args.argInt(0, 0)
+		if args.flag("details", false) {
+			runProductDetails(ctx, client, id)
+			break
+		}
 		product, err := client.GetProduct(ctx, id)
 		if err != nil {
 			fatal("Get product failed: %v", err)
 		}
+		recordSeen("product", *product)
 		printJSON(product)
 
 	case "bonus":
@@ -154,24 +192,10 @@ func main() {
 		printJSON(products)
 
 	case "receipts":
-		client := mustAuth(ctx, configPath)
-		receipts, err := client.GetReceipts(ctx)
-		if err != nil {
-			fatal("Get receipts failed: %v", err)
-		}
-		printJSON(receipts)
+		runReceipts(ctx, configPath, os.Args[2:])
 
 	case "receipt":
-		if len(os.Args) < 3 {
-			fmt.Fprintln(os.Stderr, "Usage: appie-cli receipt <transaction-id>")
-			os.Exit(1)
-		}
-		client := mustAuth(ctx, configPath)
-		receipt, err := client.GetReceipt(ctx, os.Args[2])
-		if err != nil {
-			fatal("Get receipt failed: %v", err)
-		}
-		printJSON(receipt)
+		runReceipt(ctx, configPath, os.Args[2:])
 
 	case "shopping-list":
 		client := mustAuth(ctx, configPath)
@@ -216,39 +240,18 @@ func main() {
 			if err := client.AddProductToShoppingList(ctx, id, qty); err != nil {
 				fatal("Add to list failed: %v", err)
 			}
+			recordAdditions(ctx, client, "add-to-list", map[int]int{id: qty})
 		}
 		fmt.Println(`{"ok": true}`)
 
 	case "batch-add":
-		// Reads JSON array from stdin: [{"id": 123, "qty": 2}, {"text": "free text", "qty": 1}]
-		client := mustAuth(ctx, configPath)
-		var batchItems []struct {
-			ID   int    `json:"id"`
-			Text string `json:"text"`
-			Qty  int    `json:"qty"`
-		}
-		if err := json.NewDecoder(os.Stdin).Decode(&batchItems); err != nil {
-			fatal("Invalid JSON input: %v", err)
-		}
-		items := make([]appie.ListItem, 0, len(batchItems))
-		for _, b := range batchItems {
-			qty := b.Qty
-			if qty < 1 {
-				qty = 1
-			}
-			if b.Text != "" {
-				items = append(items, appie.ListItem{Name: b.Text, Quantity: qty})
-			} else if b.ID > 0 {
-				items = append(items, appie.ListItem{ProductID: b.ID, Quantity: qty})
-			}
-		}
-		if len(items) == 0 {
-			fatal("No valid items in input")
-		}
-		if err := client.AddToShoppingList(ctx, items); err != nil {
-			fatal("Batch add failed: %v", err)
-		}
-		fmt.Printf(`{"ok": true, "added": %d}`+"\n", len(items))
+		runBatchAdd(ctx, configPath, os.Args[2:])
+
+	case "index":
+		runIndex(ctx, configPath, os.Args[2:])
+
+	case "parse-items":
+		runParseItems(os.Args[2:])
 
 	case "clear-list":
 		client := mustAuth(ctx, configPath)
@@ -279,6 +282,7 @@ func main() {
 		if err := client.AddToOrder(ctx, []appie.OrderItem{{ProductID: id, Quantity: qty}}); err != nil {
 			fatal("Add to order failed: %v", err)
 		}
+		recordAdditions(ctx, client, "add-to-order", map[int]int{id: qty})
 		fmt.Println(`{"ok": true}`)
 
 	case "list-items":
@@ -307,6 +311,14 @@ func main() {
 		if err != nil {
 			fatal("Get previously bought failed: %v", err)
 		}
+		var bought []boughtProduct
+		if json.Unmarshal(products, &bought) == nil {
+			seen := make([]appie.Product, len(bought))
+			for i, b := range bought {
+				seen[i] = productFromBought(b)
+			}
+			recordSeen("previously-bought", seen...)
+		}
 		result := map[string]any{
 			"products":      products,
 			"totalElements": total,
@@ -325,42 +337,149 @@ func main() {
 		if err != nil {
 			fatal("Get bonus products failed: %v", err)
 		}
+		var typed struct {
+			Products []bonusProduct `json:"products"`
+		}
+		if json.Unmarshal(products, &typed) == nil {
+			seen := make([]appie.Product, len(typed.Products))
+			for i, b := range typed.Products {
+				seen[i] = b.product()
+			}
+			recordSeen("bonus-products", seen...)
+		}
 		printJSON(products)
 
+	case "bonus-segments":
+		client := mustAuth(ctx, configPath)
+		segments, err := getBonusSegments(ctx, client)
+		if err != nil {
+			fatal("Get bonus segments failed: %v", err)
+		}
+		printJSON(segments)
+
+	case "bonus-segment":
+		runBonusSegment(ctx, configPath, os.Args[2:])
+
 	case "search-recipes":
+		args := parseArgs(os.Args[2:], "safe", "seasonal")
 		client := mustAnon(ctx, configPath)
-		query := ""
-		if len(os.Args) >= 3 {
-			query = os.Args[2]
-		}
-		size := 10
-		if len(os.Args) >= 4 {
-			size, _ = strconv.Atoi(os.Args[3])
+		query := args.arg(0)
+		size := args.argInt(1, 10)
+		cfg := mustSkillConfig()
+		filters := recipeFiltersFromArgs(args, cfg)
+		safety := newSafetyFilter(cfg)
+		if !args.flag("safe", true) {
+			safety = &safetyFilter{}
+		}
+		if filters.empty() && safety.empty() {
+			recipes, err := searchRecipes(ctx, client, query, size)
+			if err != nil {
+				fatal("Search recipes failed: %v", err)
+			}
+			printJSON(recipes)
+			break
 		}
-		recipes, err := searchRecipes(ctx, client, query, size)
+		result, filters, err := searchRecipesFiltered(ctx, client, query, size, filters, true)
 		if err != nil {
 			fatal("Search recipes failed: %v", err)
 		}
-		printJSON(recipes)
+		kept, excluded := selectRecipes(ctx, client, result.Result, filters, safety, false, size)
+		if !slices.ContainsFunc(recipeFilterFlags, args.has) {
+			// Without filter flags the output keeps the plain search shape;
+			// what config.json left out is reported on stderr.
+			plain := recipeSearchResult{Result: []recipeSummary{}, Page: result.Page}
+			for _, m := range kept {
+				plain.Result = append(plain.Result, m.recipeSummary)
+			}
+			for _, e := range excluded {
+				fmt.Fprintf(os.Stderr, "warning: left out recipe %d %q: %s\n", e.ID, e.Title, describeConflicts(e.Conflicts))
+			}
+			printJSON(plain)
+			break
+		}
+		printJSON(map[string]any{
+			"result":   kept,
+			"page":     result.Page,
+			"excluded": excluded,
+		})
 
 	case "recipe":
-		if len(os.Args) < 3 {
-			fmt.Fprintln(os.Stderr, "Usage: appie-cli recipe <id>")
+		args := parseArgs(os.Args[2:], "safe")
+		if len(args.pos) < 1 {
+			fmt.Fprintln(os.Stderr, "Usage: appie-cli recipe <id> [--no-safe]")
 			os.Exit(1)
 		}
 		client := mustAnon(ctx, configPath)
-		recipeID, _ := strconv.Atoi(os.Args[2])
+		recipeID := args.argInt(0, 0)
 		recipe, err := getRecipe(ctx, client, recipeID)
 		if err != nil {
 			fatal("Get recipe failed: %v", err)
 		}
+		if filter := newSafetyFilter(mustSkillConfig()); args.flag("safe", true) && !filter.empty() {
+			var details recipeDetails
+			if err := json.Unmarshal(recipe, &details); err != nil {
+				fatal("Get recipe failed: %v", err)
+			}
+			conflicts := filter.checkRecipe(&details)
+			result := map[string]any{
+				"recipe": recipe,
+				"safe":   len(conflicts) == 0,
+			}
+			if len(conflicts) > 0 {
+				result["conflicts"] = conflicts
+			}
+			printJSON(result)
+			break
+		}
 		printJSON(recipe)
 
+	case "suggest-recipes":
+		runSuggestRecipes(ctx, configPath, os.Args[2:])
+
+	case "plan-week":
+		runPlanWeek(ctx, configPath, os.Args[2:])
+
+	case "nutrition":
+		runNutrition(ctx, configPath, os.Args[2:])
+
+	case "history":
+		runHistory(ctx, configPath, os.Args[2:])
+
+	case "pantry":
+		runPantry(os.Args[2:])
+
+	case "predict":
+		runPredict(os.Args[2:])
+
+	case "reorder-meal":
+		runReorderMeal(ctx, configPath, os.Args[2:])
+
+	case "profile":
+		runProfile(ctx, configPath, os.Args[2:])
+
+	case "gql":
+		runGQL(ctx, configPath, os.Args[2:])
+
+	case "substitute":
+		runSubstitute(ctx, configPath, os.Args[2:])
+
+	case "budget":
+		runBudget(ctx, configPath, os.Args[2:])
+
+	case "stats":
+		runStats(os.Args[2:])
+
+	case "doctor":
+		runDoctor(ctx, configPath)
+
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", cmd)
 		printUsage()
 		os.Exit(1)
 	}
+
+	flushSeen()
+	flushAdditions()
 }
 
 func printUsage() {
@@ -368,22 +487,53 @@ func printUsage() {
 		"login                  Login via local web page (easiest)",
 		"login-url              Get the AH login URL (manual)",
 		"exchange-code <code>   Exchange auth code or appie:// URL for tokens",
-		"member                 Show member profile",
-		"search <query> [n]     Search products",
-		"product <id>           Get product details",
+		"member                 Show member profile (--no-redact to include name/email)",
+		"member insights        Show only AH's profile properties and audiences",
+		"member-profile         Same as member insights",
+		"search <query> [n]     Search products (--no-safe to skip allergy/dislike filter)",
+		"  --offline            Search the local index (--brand, --category, --min-price,",
+		"                       --max-price, --bonus)",
+		"index build|stats      Build the local product index from bonus and previously bought",
+		"product <id>           Get product details (--details: nutrition, allergens,",
+		"                       ingredients, checked against config.json)",
 		"bonus                  Get spotlight bonus products",
-		"receipts               List receipts (kassabonnen)",
+		"bonus-segments         List bonus segments (promotions)",
+		"bonus-segment <id>     Show a bonus segment with its products",
+		"receipts               List receipts (kassabonnen, --no-redact, --local)",
+		"receipts import <file>... Import receipts from JSON exports or PDF text",
 		"receipt <id>           Get receipt details",
 		"shopping-list          Show shopping list",
 		"shopping-lists         List all shopping lists",
 		"add-to-list <id> [qty] Add product to shopping list",
 		"add-to-list --text \"item\" [qty]  Add free text item",
-		"batch-add              Add multiple items from stdin (JSON array)",
+		"batch-add              Add multiple items from stdin (JSON array, CSV or text lines,",
+		"                       --format, --first-match, --interactive, --no-substitute,",
+		"                       --strict, --dry-run)",
+		"parse-items [text]     Parse Dutch/English grocery text into name/qty/unit (--batch)",
+		"substitute <id> [n]    Find available alternatives for a product (--name, --no-safe)",
 		"clear-list             Clear shopping list",
 		"order                  Show current order",
 		"add-to-order <id> [qty] Add product to order",
-		"search-recipes [query] [n] Search Allerhande recipes",
-		"recipe <id>            Get recipe with ingredients",
+		"search-recipes [query] [n] Search Allerhande recipes (--no-safe to skip filter)",
+		"  --max-time <min> --min-servings <n> --tags a,b --exclude-tags a,b",
+		"  --cuisine <c> --course <c> --diet vegetarian|vegan --sort relevance|time",
+		"  --seasonal [--month <1-12>]  rank by in-season Dutch produce",
+		"recipe <id>            Get recipe with ingredients (--no-safe to skip filter)",
+		"suggest-recipes [query] [n] Rank recipes by bonus savings (search-recipes flags, --candidates <n>)",
+		"plan-week              Propose a week of meals + shopping payload (--meals <n> --avoid-weeks <n>",
+		"                       --biweekly --no-resolve --no-pantry --markdown --out <prefix>)",
+		"nutrition <id>...      Per-person macros for recipes, flags salt/sugar/saturated fat",
+		"                       over nutrition_limits (--plan proposal.json, --leftovers)",
+		"history approve|reject|feedback|list  Manage meal-history.json",
+		"pantry add|use|list|expire  Track what's in the house in pantry.json, with run-out estimates",
+		"predict                Products due this week from purchase history, with confidence",
+		"                       (--days <n> --min-confidence <0-1> --all --batch --no-pantry)",
+		"reorder-meal <recipe-id|date> Re-add a past meal's products (--order, --dry-run, --no-pantry)",
+		"profile build          Generate taste-profile.md and taste-profile.json",
+		"budget check           Price the list (or --order) against weekly_budget, suggest cheaper swaps",
+		"stats spending         Spend per week/month/category/brand/bonus (--since, --source, --csv)",
+		"doctor                 Check every built-in GraphQL query against the API",
+		"gql [file|-]           Run a GraphQL query from a file or stdin (--variables '{...}', --anon)",
 	}
 	fmt.Fprintln(os.Stderr, "Usage: appie-cli <command> [args]")
 	fmt.Fprintln(os.Stderr, "")
@@ -447,52 +597,77 @@ func getListItems(ctx context.Context, client *appie.Client, listID string) (jso
 
 	body, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("API error: %d %s", resp.StatusCode, string(body))
+		return nil, &httpError{Status: resp.StatusCode, Body: string(body)}
 	}
 	return body, nil
 }
 
 // getPreviouslyBought fetches previously bought products via GraphQL
 func getPreviouslyBought(ctx context.Context, client *appie.Client, size, page int) (json.RawMessage, int, error) {
-	query := fmt.Sprintf(`{ productSearch(input: { query: "" previouslyBought: true size: %d page: %d }) { products { id title brand category } page { totalElements totalPages } } }`, size, page)
-
-	reqBody, _ := json.Marshal(map[string]string{"query": query})
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.ah.nl/graphql", bytes.NewReader(reqBody))
-	if err != nil {
-		return nil, 0, err
+	var data struct {
+		ProductSearch struct {
+			Products json.RawMessage `json:"products"`
+			Page     struct {
+				TotalElements int `json:"totalElements"`
+			} `json:"page"`
+		} `json:"productSearch"`
 	}
-	req.Header.Set("Authorization", "Bearer "+client.AccessToken())
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-client-name", "appie-ios")
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
+	if err := runCatalogQuery(ctx, client, "previously-bought", map[string]any{"size": size, "page": page}, &data); err != nil {
 		return nil, 0, err
 	}
-	defer resp.Body.Close()
+	return data.ProductSearch.Products, data.ProductSearch.Page.TotalElements, nil
+}
 
-	body, _ := io.ReadAll(resp.Body)
+// boughtProduct is a product from getPreviouslyBought.
+type boughtProduct struct {
+	ID       int    `json:"id"`
+	Title    string `json:"title"`
+	Brand    string `json:"brand"`
+	Category string `json:"category"`
+}
 
-	var result struct {
-		Data struct {
-			ProductSearch struct {
-				Products json.RawMessage `json:"products"`
-				Page     struct {
-					TotalElements int `json:"totalElements"`
-				} `json:"page"`
-			} `json:"productSearch"`
-		} `json:"data"`
-	}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, 0, fmt.Errorf("parse error: %w", err)
+// getAllPreviouslyBought pages through the full purchase history.
+func getAllPreviouslyBought(ctx context.Context, client *appie.Client) ([]boughtProduct, error) {
+	const size = 100
+	var all []boughtProduct
+	for page := 0; page < 50; page++ {
+		raw, total, err := getPreviouslyBought(ctx, client, size, page)
+		if err != nil {
+			return nil, err
+		}
+		var products []boughtProduct
+		if err := json.Unmarshal(raw, &products); err != nil {
+			return nil, fmt.Errorf("parse error: %w", err)
+		}
+		all = append(all, products...)
+		if len(products) < size || len(all) >= total {
+			break
+		}
 	}
-	return result.Data.ProductSearch.Products, result.Data.ProductSearch.Page.TotalElements, nil
+	return all, nil
 }
 
 // getBonusProducts fetches current bonus products via REST
 func getBonusProducts(ctx context.Context, client *appie.Client, size int) (json.RawMessage, error) {
-	url := fmt.Sprintf("https://api.ah.nl/mobile-services/product/search/v2?bonus=true&size=%d&sortOn=RELEVANCE", size)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	return getREST(ctx, client, fmt.Sprintf("/mobile-services/product/search/v2?bonus=true&size=%d&sortOn=RELEVANCE", size))
+}
+
+// httpError is a non-200 response to getREST or graphqlQuery. appie-go's
+// errors drop the status when the body is JSON, so use getREST where the
+// status matters.
+type httpError struct {
+	Status int
+	Body   string
+}
+
+func (e *httpError) Error() string {
+	return fmt.Sprintf("API error: %d %s", e.Status, e.Body)
+}
+
+// getREST GETs a mobile-services path with the client's token, for
+// endpoints appie-go doesn't expose.
+func getREST(ctx context.Context, client *appie.Client, path string) (json.RawMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", restBaseURL()+path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -509,7 +684,7 @@ func getBonusProducts(ctx context.Context, client *appie.Client, size int) (json
 
 	body, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("API error: %d %s", resp.StatusCode, string(body))
+		return nil, &httpError{Status: resp.StatusCode, Body: string(body)}
 	}
 	return body, nil
 }
@@ -610,10 +785,36 @@ const successPage = `<!DOCTYPE html>
 </body>
 </html>`
 
-// graphqlQuery executes a GraphQL query and returns the raw response body
-func graphqlQuery(ctx context.Context, client *appie.Client, query string) ([]byte, error) {
-	reqBody, _ := json.Marshal(map[string]string{"query": query})
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.ah.nl/graphql", bytes.NewReader(reqBody))
+// graphqlURL is the GraphQL endpoint; APPIE_GRAPHQL_URL points the CLI at a
+// fake server for testing.
+func graphqlURL() string {
+	if v := os.Getenv("APPIE_GRAPHQL_URL"); v != "" {
+		return v
+	}
+	return "https://api.ah.nl/graphql"
+}
+
+// restBaseURL is the host getREST talks to; APPIE_REST_URL points it at a
+// fake server for testing.
+func restBaseURL() string {
+	if v := os.Getenv("APPIE_REST_URL"); v != "" {
+		return v
+	}
+	return "https://api.ah.nl"
+}
+
+// graphqlQuery executes a GraphQL query and returns the raw response body.
+// Values are passed as variables rather than formatted into the query.
+func graphqlQuery(ctx context.Context, client *appie.Client, query string, variables map[string]any) ([]byte, error) {
+	payload := map[string]any{"query": query}
+	if len(variables) > 0 {
+		payload["variables"] = variables
+	}
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", graphqlURL(), bytes.NewReader(reqBody))
 	if err != nil {
 		return nil, err
 	}
@@ -629,99 +830,31 @@ func graphqlQuery(ctx context.Context, client *appie.Client, query string) ([]by
 
 	body, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("API error: %d %s", resp.StatusCode, string(body))
+		return nil, &httpError{Status: resp.StatusCode, Body: string(body)}
 	}
 	return body, nil
 }
 
 // searchRecipes searches Allerhande recipes via GraphQL
 func searchRecipes(ctx context.Context, client *appie.Client, query string, size int) (json.RawMessage, error) {
-	gql := fmt.Sprintf(`{
-		recipeSearch(query: { query: %q, size: %d }) {
-			result {
-				id
-				title
-				slug
-				cookTime
-				images {
-					rendition { url }
-				}
-			}
-			page { totalElements totalPages }
-		}
-	}`, query, size)
-
-	body, err := graphqlQuery(ctx, client, gql)
-	if err != nil {
-		return nil, err
+	var data struct {
+		RecipeSearch json.RawMessage `json:"recipeSearch"`
 	}
-
-	var result struct {
-		Data struct {
-			RecipeSearch json.RawMessage `json:"recipeSearch"`
-		} `json:"data"`
-		Errors json.RawMessage `json:"errors"`
-	}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("parse error: %w\nraw: %s", err, string(body))
-	}
-	if result.Errors != nil {
-		return nil, fmt.Errorf("GraphQL errors: %s", string(result.Errors))
+	if err := runCatalogQuery(ctx, client, "recipe-search", map[string]any{"query": query, "size": size}, &data); err != nil {
+		return nil, err
 	}
-	return result.Data.RecipeSearch, nil
+	return data.RecipeSearch, nil
 }
 
 // getRecipe fetches a single recipe with full details via GraphQL
 func getRecipe(ctx context.Context, client *appie.Client, id int) (json.RawMessage, error) {
-	gql := fmt.Sprintf(`{
-		recipe(id: %d) {
-			id
-			title
-			slug
-			description
-			cookTime
-			prepTime
-			servings
-			tags
-			ingredients {
-				text
-				quantity
-				name { singular plural }
-				unit { singular plural }
-			}
-			steps {
-				text
-				index
-			}
-			nutritions {
-				name
-				value
-				unit
-			}
-			images {
-				rendition { url }
-			}
-		}
-	}`, id)
-
-	body, err := graphqlQuery(ctx, client, gql)
-	if err != nil {
-		return nil, err
+	var data struct {
+		Recipe json.RawMessage `json:"recipe"`
 	}
-
-	var result struct {
-		Data struct {
-			Recipe json.RawMessage `json:"recipe"`
-		} `json:"data"`
-		Errors json.RawMessage `json:"errors"`
-	}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("parse error: %w\nraw: %s", err, string(body))
-	}
-	if result.Errors != nil {
-		return nil, fmt.Errorf("GraphQL errors: %s", string(result.Errors))
+	if err := runCatalogQuery(ctx, client, "recipe", map[string]any{"id": id}, &data); err != nil {
+		return nil, err
 	}
-	return result.Data.Recipe, nil
+	return data.Recipe, nil
 }
 
 func fatal(format string, args ...any) {
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// mealHistory mirrors meal-history.json (see meal-history-template.json).
+// The file is edited by hand as well, so fields this CLI does not know are
+// kept in extra, here and in every entry, and written back on save.
+type mealHistory struct {
+	Approved []approvedMeal  `json:"approved"`
+	Rejected []rejectedMeal  `json:"rejected"`
+	Feedback []mealFeedback  `json:"feedback"`
+	Schema   json.RawMessage `json:"_schema,omitempty"`
+
+	extra extraFields
+}
+
+// approvedMeal follows _schema.approved_entry. product_ids maps ingredient
+// name to the AH product that was added for it.
+type approvedMeal struct {
+	Date        string         `json:"date"`
+	RecipeName  string         `json:"recipe_name"`
+	RecipeID    int            `json:"recipe_id"`
+	CookingTime int            `json:"cooking_time"`
+	Ingredients []string       `json:"ingredients"`
+	ProductIDs  map[string]int `json:"product_ids"`
+	Notes       string         `json:"notes"`
+	Tags        []string       `json:"tags,omitempty"`
+
+	extra extraFields
+}
+
+type rejectedMeal struct {
+	Date       string   `json:"date"`
+	RecipeName string   `json:"recipe_name"`
+	RecipeID   int      `json:"recipe_id"`
+	Reason     string   `json:"reason"`
+	Tags       []string `json:"tags,omitempty"`
+
+	extra extraFields
+}
+
+type mealFeedback struct {
+	Date       string `json:"date"`
+	RecipeName string `json:"recipe_name,omitempty"`
+	RecipeID   int    `json:"recipe_id,omitempty"`
+	Text       string `json:"text"`
+
+	extra extraFields
+}
+
+func (h *mealHistory) UnmarshalJSON(data []byte) error {
+	type plain mealHistory
+	return unmarshalExtra(data, (*plain)(h), &h.extra)
+}
+
+func (h mealHistory) MarshalJSON() ([]byte, error) {
+	type plain mealHistory
+	return marshalExtra(plain(h), h.extra)
+}
+
+func (m *approvedMeal) UnmarshalJSON(data []byte) error {
+	type plain approvedMeal
+	return unmarshalExtra(data, (*plain)(m), &m.extra)
+}
+
+func (m approvedMeal) MarshalJSON() ([]byte, error) {
+	type plain approvedMeal
+	return marshalExtra(plain(m), m.extra)
+}
+
+func (m *rejectedMeal) UnmarshalJSON(data []byte) error {
+	type plain rejectedMeal
+	return unmarshalExtra(data, (*plain)(m), &m.extra)
+}
+
+func (m rejectedMeal) MarshalJSON() ([]byte, error) {
+	type plain rejectedMeal
+	return marshalExtra(plain(m), m.extra)
+}
+
+func (f *mealFeedback) UnmarshalJSON(data []byte) error {
+	type plain mealFeedback
+	return unmarshalExtra(data, (*plain)(f), &f.extra)
+}
+
+func (f mealFeedback) MarshalJSON() ([]byte, error) {
+	type plain mealFeedback
+	return marshalExtra(plain(f), f.extra)
+}
+
+// extraFields are the members of a JSON object its Go type has no field for.
+type extraFields map[string]json.RawMessage
+
+// unmarshalExtra decodes data into the struct v points to and collects the
+// members it has no field for in extra.
+func unmarshalExtra(data []byte, v any, extra *extraFields) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	var all extraFields
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+	for _, name := range jsonFieldNames(reflect.TypeOf(v).Elem()) {
+		delete(all, name)
+	}
+	if len(all) > 0 {
+		*extra = all
+	}
+	return nil
+}
+
+// marshalExtra encodes the struct v and appends the extra members, sorted,
+// that v did not write itself.
+func marshalExtra(v any, extra extraFields) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+	known := map[string]bool{}
+	for _, name := range jsonFieldNames(reflect.TypeOf(v)) {
+		known[name] = true
+	}
+	names := make([]string, 0, len(extra))
+	for name := range extra {
+		if !known[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var b bytes.Buffer
+	b.Write(data[:len(data)-1]) // without the closing brace
+	for _, name := range names {
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(extra[name])
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// jsonFieldNames lists the JSON member names of a struct type's exported
+// fields.
+func jsonFieldNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = f.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// loadMealHistory reads meal-history.json; a missing file is an empty history.
+func loadMealHistory() (*mealHistory, error) {
+	h := &mealHistory{}
+	data, err := os.ReadFile(skillPath("meal-history.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, fmt.Errorf("parse meal-history.json: %w", err)
+	}
+	return h, nil
+}
+
+// save writes meal-history.json, keeping the _schema block and fields added
+// by hand.
+func (h *mealHistory) save() error {
+	if h.Approved == nil {
+		h.Approved = []approvedMeal{}
+	}
+	if h.Rejected == nil {
+		h.Rejected = []rejectedMeal{}
+	}
+	if h.Feedback == nil {
+		h.Feedback = []mealFeedback{}
+	}
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(skillPath("meal-history.json"), append(data, '\n'), 0o644)
+}
+
+// validate checks every entry against _schema and returns the problems found.
+func (h *mealHistory) validate() []string {
+	var issues []string
+	check := func(kind string, i int, date, name string, recipeID int) {
+		if _, err := time.Parse(dateLayout, date); err != nil {
+			issues = append(issues, fmt.Sprintf("%s[%d]: date %q is not YYYY-MM-DD", kind, i, date))
+		}
+		if name == "" && recipeID == 0 {
+			issues = append(issues, fmt.Sprintf("%s[%d]: needs recipe_name or recipe_id", kind, i))
+		}
+	}
+	for i, m := range h.Approved {
+		check("approved", i, m.Date, m.RecipeName, m.RecipeID)
+		for name, id := range m.ProductIDs {
+			if id <= 0 {
+				issues = append(issues, fmt.Sprintf("approved[%d]: product_ids[%q] is not a product ID", i, name))
+			}
+		}
+	}
+	for i, m := range h.Rejected {
+		check("rejected", i, m.Date, m.RecipeName, m.RecipeID)
+		if m.Reason == "" {
+			issues = append(issues, fmt.Sprintf("rejected[%d]: missing reason", i))
+		}
+	}
+	for i, f := range h.Feedback {
+		if _, err := time.Parse(dateLayout, f.Date); err != nil {
+			issues = append(issues, fmt.Sprintf("feedback[%d]: date %q is not YYYY-MM-DD", i, f.Date))
+		}
+		if f.Text == "" {
+			issues = append(issues, fmt.Sprintf("feedback[%d]: missing text", i))
+		}
+	}
+	return issues
+}
+
+// recentRecipes returns the IDs of recipes approved on or after since.
+func (h *mealHistory) recentRecipes(since time.Time) map[int]bool {
+	ids := map[int]bool{}
+	for _, m := range h.Approved {
+		d, err := time.Parse(dateLayout, m.Date)
+		if err != nil || m.RecipeID == 0 {
+			continue
+		}
+		if !d.Before(since) {
+			ids[m.RecipeID] = true
+		}
+	}
+	return ids
+}
+
+// rejectedRecipes returns the IDs of every rejected recipe.
+func (h *mealHistory) rejectedRecipes() map[int]bool {
+	ids := map[int]bool{}
+	for _, m := range h.Rejected {
+		if m.RecipeID != 0 {
+			ids[m.RecipeID] = true
+		}
+	}
+	return ids
+}
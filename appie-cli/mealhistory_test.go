@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMealHistoryKeepsUnknownFields(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("APPIE_SKILL_DIR", dir)
+	in := `{
+  "approved": [
+    {"date": "2026-03-02", "recipe_name": "Curry", "recipe_id": 1, "cooking_time": 30,
+     "ingredients": [], "product_ids": {}, "notes": "", "rating": 5, "cooked_by": "Sam"}
+  ],
+  "rejected": [{"date": "2026-03-03", "recipe_name": "Soep", "recipe_id": 2, "reason": "te zout", "season": "winter"}],
+  "feedback": [{"date": "2026-03-04", "text": "meer vis", "from": "kids"}],
+  "_schema": {"approved_entry": {}},
+  "version": 2,
+  "notes": {"diet": "minder vlees"}
+}`
+	path := filepath.Join(dir, "meal-history.json")
+	if err := os.WriteFile(path, []byte(in), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h, err := loadMealHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Feedback = append(h.Feedback, mealFeedback{Date: "2026-03-05", Text: "lekker"})
+	if err := h.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("saved file is not JSON: %v\n%s", err, data)
+	}
+	entry := func(kind string, i int) map[string]any {
+		return got[kind].([]any)[i].(map[string]any)
+	}
+	checks := []struct {
+		name      string
+		got, want any
+	}{
+		{"version", got["version"], 2.0},
+		{"notes", got["notes"], map[string]any{"diet": "minder vlees"}},
+		{"_schema", got["_schema"], map[string]any{"approved_entry": map[string]any{}}},
+		{"approved rating", entry("approved", 0)["rating"], 5.0},
+		{"approved cooked_by", entry("approved", 0)["cooked_by"], "Sam"},
+		{"approved recipe_name", entry("approved", 0)["recipe_name"], "Curry"},
+		{"rejected season", entry("rejected", 0)["season"], "winter"},
+		{"feedback from", entry("feedback", 0)["from"], "kids"},
+		{"new feedback", entry("feedback", 1), map[string]any{"date": "2026-03-05", "text": "lekker"}},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+
+	appie "github.com/gwillem/appie-go"
+)
+
+// memberProfile is AH's segmentation of the member. It carries no name,
+// email or address.
+type memberProfile struct {
+	Audiences  []string          `json:"customerProfileAudiences"`
+	Properties []profileProperty `json:"customerProfileProperties"`
+}
+
+type profileProperty struct {
+	Key   string `json:"key"`
+	Value any    `json:"value"`
+}
+
+// getMemberProfile fetches customerProfileAudiences and
+// customerProfileProperties via GraphQL
+func getMemberProfile(ctx context.Context, client *appie.Client) (*memberProfile, error) {
+	var data struct {
+		Member memberProfile `json:"member"`
+	}
+	if err := runCatalogQuery(ctx, client, "member-profile", nil, &data); err != nil {
+		return nil, err
+	}
+	return &data.Member, nil
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// nutrientKeys maps words in Allerhande's nutrition names (Dutch or
+// English) to output keys. Order matters: "koolhydraten waarvan suikers"
+// and "verzadigd vet" must match before "koolhydra" and "vet", and
+// "onverzadigd vet" before "verzadigd". An empty key skips the nutrient.
+var nutrientKeys = []struct{ word, key string }{
+	{"onverzadigd", ""},
+	{"unsaturated", ""},
+	{"energie", "energy_kcal"},
+	{"energy", "energy_kcal"},
+	{"calorie", "energy_kcal"},
+	{"eiwit", "protein"},
+	{"protein", "protein"},
+	{"suiker", "sugars"},
+	{"sugar", "sugars"},
+	{"koolhydra", "carbohydrates"},
+	{"carbohydrate", "carbohydrates"},
+	{"verzadigd", "saturated_fat"},
+	{"saturated", "saturated_fat"},
+	{"vet", "fat"},
+	{"fat", "fat"},
+	{"vezel", "fibre"},
+	{"fib", "fibre"},
+	{"zout", "salt"},
+	{"salt", "salt"},
+	{"natrium", "sodium"},
+	{"sodium", "sodium"},
+}
+
+// nutrientKey normalises a recipe nutrition to an output key and a value in
+// grams (kcal for energy). ok is false for nutrients this report skips.
+func nutrientKey(n recipeNutrition) (key string, value float64, ok bool) {
+	name := strings.ToLower(n.Name)
+	for _, k := range nutrientKeys {
+		if strings.Contains(name, k.word) {
+			key = k.key
+			break
+		}
+	}
+	if key == "" {
+		return "", 0, false
+	}
+	value = n.Value
+	switch strings.ToLower(n.Unit) {
+	case "mg":
+		value /= 1000
+	case "kj":
+		value /= 4.184
+	}
+	return key, value, true
+}
+
+// recipeNutrients returns a recipe's nutrition per serving. Salt is derived
+// from sodium (x 2.5) when only sodium is given.
+func recipeNutrients(d *recipeDetails) map[string]float64 {
+	out := map[string]float64{}
+	for _, n := range d.Nutritions {
+		if key, v, ok := nutrientKey(n); ok {
+			out[key] = v
+		}
+	}
+	if _, ok := out["salt"]; !ok && out["sodium"] > 0 {
+		out["salt"] = out["sodium"] * 2.5
+	}
+	delete(out, "sodium")
+	return out
+}
+
+// mealNutrition is one recipe's share per person.
+type mealNutrition struct {
+	ID        int                `json:"id"`
+	Title     string             `json:"title"`
+	Servings  int                `json:"servings"`
+	Packages  int                `json:"packages"`
+	Factor    float64            `json:"factor"`
+	PerPerson map[string]float64 `json:"perPerson"`
+	Flags     []string           `json:"flags,omitempty"`
+}
+
+// nutritionReport is the output of nutrition.
+type nutritionReport struct {
+	HouseholdSize int                `json:"householdSize"`
+	Meals         []mealNutrition    `json:"meals"`
+	Weekly        map[string]float64 `json:"perPersonWeekly"`
+	Daily         map[string]float64 `json:"perPersonDaily"`
+	PerMeal       map[string]float64 `json:"perPersonPerMeal"`
+	MealLimits    map[string]float64 `json:"mealLimits"`
+	Flags         []string           `json:"flags,omitempty"`
+	Missing       []int              `json:"missing,omitempty"`
+	OK            bool               `json:"ok"`
+}
+
+// mealLimits turns the daily limits in config.json into limits for one
+// dinner per person.
+func mealLimits(cfg *skillConfig) map[string]float64 {
+	l := cfg.NutritionLimits
+	share := l.MealShare
+	if share <= 0 || share > 1 {
+		share = 1
+	}
+	limits := map[string]float64{}
+	for key, v := range map[string]float64{"salt": l.Salt, "sugars": l.Sugars, "saturated_fat": l.SaturatedFat} {
+		if v > 0 {
+			limits[key] = round1(v * share)
+		}
+	}
+	return limits
+}
+
+// overLimits describes the nutrients in values above limits.
+func overLimits(values, limits map[string]float64) []string {
+	keys := make([]string, 0, len(limits))
+	for k := range limits {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var flags []string
+	for _, k := range keys {
+		if values[k] > limits[k] {
+			flags = append(flags, fmt.Sprintf("%s %.1f g per person, over the %.1f g meal limit", strings.ReplaceAll(k, "_", " "), values[k], limits[k]))
+		}
+	}
+	return flags
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+func roundAll(m map[string]float64) map[string]float64 {
+	for k, v := range m {
+		m[k] = round1(v)
+	}
+	return m
+}
+
+// nutritionStats sums the recipes per person. Allerhande gives nutrition
+// per serving; a recipe cooked in packages x servings for the household is
+// eaten up unless leftovers is set, so each person gets
+// servings*packages/household servings. Daily values spread the week's
+// dinners over 7 days.
+func nutritionStats(cfg *skillConfig, details []*recipeDetails, packages map[int]int, leftovers bool) *nutritionReport {
+	household := max(cfg.HouseholdSize, 1)
+	limits := mealLimits(cfg)
+	r := &nutritionReport{
+		HouseholdSize: household,
+		Meals:         []mealNutrition{},
+		Weekly:        map[string]float64{},
+		Daily:         map[string]float64{},
+		PerMeal:       map[string]float64{},
+		MealLimits:    limits,
+	}
+	for _, d := range details {
+		perServing := recipeNutrients(d)
+		if len(perServing) == 0 {
+			r.Missing = append(r.Missing, d.ID)
+			continue
+		}
+		servings := max(d.Servings, 1)
+		pkgs := packages[d.ID]
+		if pkgs <= 0 {
+			pkgs = int(math.Ceil(float64(household) / float64(servings)))
+		}
+		factor := float64(servings*pkgs) / float64(household)
+		if leftovers || factor < 1 {
+			factor = 1
+		}
+		m := mealNutrition{ID: d.ID, Title: d.Title, Servings: servings, Packages: pkgs, Factor: round1(factor), PerPerson: map[string]float64{}}
+		for k, v := range perServing {
+			m.PerPerson[k] = v * factor
+			r.Weekly[k] += v * factor
+		}
+		m.Flags = overLimits(m.PerPerson, limits)
+		roundAll(m.PerPerson)
+		r.Meals = append(r.Meals, m)
+	}
+	if n := len(r.Meals); n > 0 {
+		for k, v := range r.Weekly {
+			r.Daily[k] = v / 7
+			r.PerMeal[k] = v / float64(n)
+		}
+		for _, f := range overLimits(r.PerMeal, limits) {
+			r.Flags = append(r.Flags, "average "+f)
+		}
+	}
+	for _, m := range r.Meals {
+		if len(m.Flags) > 0 {
+			r.Flags = append(r.Flags, fmt.Sprintf("%s: %s", m.Title, strings.Join(m.Flags, "; ")))
+		}
+	}
+	roundAll(r.Weekly)
+	roundAll(r.Daily)
+	roundAll(r.PerMeal)
+	r.OK = len(r.Flags) == 0
+	return r
+}
+
+// readPlanMeals reads the recipe IDs and packages from a plan-week proposal.
+func readPlanMeals(path string) ([]int, map[int]int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var plan weekPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	ids := make([]int, 0, len(plan.Meals))
+	packages := map[int]int{}
+	for _, m := range plan.Meals {
+		ids = append(ids, m.ID)
+		packages[m.ID] = m.Packages
+	}
+	return ids, packages, nil
+}
+
+func runNutrition(ctx context.Context, configPath string, argv []string) {
+	args := parseArgs(argv, "leftovers")
+	var ids []int
+	packages := map[int]int{}
+	if path := args.str("plan", ""); path != "" {
+		var err error
+		if ids, packages, err = readPlanMeals(path); err != nil {
+			fatal("Read plan failed: %v", err)
+		}
+	}
+	for _, a := range args.pos {
+		for _, s := range strings.Split(a, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				fatal("Invalid recipe id %q", s)
+			}
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: appie-cli nutrition <recipe-id>... [--plan proposal.json] [--leftovers]")
+		os.Exit(1)
+	}
+	cfg := mustSkillConfig()
+	client := mustAnon(ctx, configPath)
+
+	details, errs := getRecipesDetails(ctx, client, ids)
+	var found []*recipeDetails
+	for i, d := range details {
+		if errs[i] != nil {
+			fatal("Get recipe %d failed: %v", ids[i], errs[i])
+		}
+		found = append(found, d)
+	}
+	printJSON(nutritionStats(cfg, found, packages, args.flag("leftovers", false)))
+}
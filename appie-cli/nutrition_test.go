@@ -0,0 +1,82 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNutrientKey(t *testing.T) {
+	tests := []struct {
+		name, unit string
+		value      float64
+		key        string
+		want       float64
+	}{
+		{"Energie", "kcal", 520, "energy_kcal", 520},
+		{"Energie", "kJ", 4184, "energy_kcal", 1000},
+		{"Eiwit", "g", 30, "protein", 30},
+		{"Koolhydraten", "g", 60, "carbohydrates", 60},
+		{"Koolhydraten, waarvan suikers", "g", 12, "sugars", 12},
+		{"Vet", "g", 20, "fat", 20},
+		{"Vet, waarvan verzadigd", "g", 6, "saturated_fat", 6},
+		{"Verzadigd vet", "g", 6, "saturated_fat", 6},
+		{"Onverzadigd vet", "g", 10, "", 0},
+		{"Vet, waarvan enkelvoudig onverzadigd", "g", 8, "", 0},
+		{"Saturated fat", "g", 4, "saturated_fat", 4},
+		{"Unsaturated fat", "g", 9, "", 0},
+		{"Voedingsvezel", "g", 7, "fibre", 7},
+		{"Zout", "g", 2.5, "salt", 2.5},
+		{"Natrium", "mg", 800, "sodium", 0.8},
+		{"Vitamine C", "mg", 40, "", 0},
+	}
+	for _, tt := range tests {
+		key, v, ok := nutrientKey(recipeNutrition{Name: tt.name, Value: tt.value, Unit: tt.unit})
+		if key != tt.key || ok != (tt.key != "") || round1(v) != round1(tt.want) {
+			t.Errorf("nutrientKey(%q %g %s) = %q %g %v, want %q %g", tt.name, tt.value, tt.unit, key, v, ok, tt.key, tt.want)
+		}
+	}
+}
+
+func TestRecipeNutrients(t *testing.T) {
+	d := &recipeDetails{Nutritions: []recipeNutrition{
+		{Name: "Vet, waarvan verzadigd", Value: 6, Unit: "g"},
+		{Name: "Vet, waarvan onverzadigd", Value: 14, Unit: "g"},
+		{Name: "Natrium", Value: 400, Unit: "mg"},
+	}}
+	want := map[string]float64{"saturated_fat": 6, "salt": 1}
+	if got := recipeNutrients(d); !reflect.DeepEqual(got, want) {
+		t.Errorf("recipeNutrients = %v, want %v", got, want)
+	}
+}
+
+func TestNutritionStats(t *testing.T) {
+	cfg := &skillConfig{HouseholdSize: 2}
+	cfg.NutritionLimits.Salt = 6
+	cfg.NutritionLimits.MealShare = 0.5 // 3 g salt per dinner
+	details := []*recipeDetails{
+		{ID: 1, Title: "Curry", Servings: 4, Nutritions: []recipeNutrition{{Name: "Zout", Value: 2, Unit: "g"}, {Name: "Energie", Value: 600, Unit: "kcal"}}},
+		{ID: 2, Title: "Soep", Servings: 2, Nutritions: []recipeNutrition{{Name: "Zout", Value: 2, Unit: "g"}, {Name: "Energie", Value: 400, Unit: "kcal"}}},
+		{ID: 3, Title: "Zonder info", Servings: 2},
+	}
+
+	// Curry serves 4: eaten up by 2 people, each gets two servings.
+	r := nutritionStats(cfg, details, map[int]int{}, false)
+	if len(r.Meals) != 2 || !reflect.DeepEqual(r.Missing, []int{3}) {
+		t.Fatalf("meals %d, missing %v; want 2 meals, missing [3]", len(r.Meals), r.Missing)
+	}
+	if got := r.Meals[0]; got.Factor != 2 || got.PerPerson["salt"] != 4 || len(got.Flags) != 1 {
+		t.Errorf("Curry = factor %g, salt %g, flags %v; want factor 2, salt 4, one flag", got.Factor, got.PerPerson["salt"], got.Flags)
+	}
+	if r.Weekly["energy_kcal"] != 1600 || r.Daily["energy_kcal"] != 228.6 || r.PerMeal["salt"] != 3 {
+		t.Errorf("weekly kcal %g, daily kcal %g, salt per meal %g; want 1600, 228.6, 3", r.Weekly["energy_kcal"], r.Daily["energy_kcal"], r.PerMeal["salt"])
+	}
+	if r.OK {
+		t.Error("report OK with a meal over the salt limit")
+	}
+
+	// With leftovers each person eats one serving.
+	r = nutritionStats(cfg, details, map[int]int{}, true)
+	if r.Meals[0].Factor != 1 || r.Weekly["salt"] != 4 || !r.OK {
+		t.Errorf("leftovers: factor %g, weekly salt %g, ok %v; want 1, 4, true", r.Meals[0].Factor, r.Weekly["salt"], r.OK)
+	}
+}
@@ -0,0 +1,452 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/markooms/openclaw-skill-albert-heijn/appie-cli/parser"
+)
+
+// pantry mirrors pantry.json: what is in the house, so plan-week can skip
+// it.
+type pantry struct {
+	Items []pantryItem `json:"items"`
+}
+
+// pantryItem is one product in stock. Qty is in Unit (a package, g, ml or
+// a plain count). Staples are kept at zero when used up, so plan-week
+// re-adds them.
+type pantryItem struct {
+	Name      string  `json:"name"`
+	ProductID int     `json:"productId,omitempty"`
+	Qty       float64 `json:"qty"`
+	Unit      string  `json:"unit,omitempty"`
+	Added     string  `json:"added"`
+	Expires   string  `json:"expires,omitempty"`
+	Staple    bool    `json:"staple,omitempty"`
+}
+
+// pantryLowDays is how far ahead an estimated run-out makes an item low.
+const pantryLowDays = 7
+
+// loadPantry reads pantry.json; a missing file is an empty pantry.
+func loadPantry() (*pantry, error) {
+	p := &pantry{}
+	data, err := os.ReadFile(skillPath("pantry.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("parse pantry.json: %w", err)
+	}
+	return p, nil
+}
+
+// save writes pantry.json sorted by name.
+func (p *pantry) save() error {
+	if p.Items == nil {
+		p.Items = []pantryItem{}
+	}
+	sort.SliceStable(p.Items, func(i, j int) bool { return p.Items[i].Name < p.Items[j].Name })
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(skillPath("pantry.json"), append(data, '\n'), 0o644)
+}
+
+// find returns the index of the item ref refers to: a product ID or a name,
+// compared after stemming ("uien" finds "ui"). It returns -1 if there is
+// none.
+func (p *pantry) find(ref string) int {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return slices.IndexFunc(p.Items, func(it pantryItem) bool { return it.ProductID == id })
+	}
+	key := strings.Join(indexTerms(ref), " ")
+	return slices.IndexFunc(p.Items, func(it pantryItem) bool {
+		return strings.Join(indexTerms(it.Name), " ") == key
+	})
+}
+
+// expired reports whether the expiry date is before today, a date at
+// midnight UTC like the dates it is compared with.
+func (it pantryItem) expired(today time.Time) bool {
+	t, err := time.Parse(dateLayout, it.Expires)
+	return err == nil && today.After(t)
+}
+
+// dateOnly is the calendar day of t, as time.Parse(dateLayout) returns it.
+func dateOnly(t time.Time) time.Time {
+	d, _ := time.Parse(dateLayout, t.Format(dateLayout))
+	return d
+}
+
+// covers reports whether the item stands for an ingredient or product: the
+// same product ID, or every word of the item's name in the ingredient
+// ("olijfolie" covers "olijfolie extra vierge").
+func (it pantryItem) covers(name string, id int) bool {
+	if id > 0 && it.ProductID == id {
+		return true
+	}
+	if name == "" {
+		return false
+	}
+	terms := indexTerms(name)
+	want := indexTerms(it.Name)
+	if len(want) == 0 {
+		return false
+	}
+	for _, t := range want {
+		if !slices.Contains(terms, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// pantryStatus is an item with its expiry and depletion estimate. The
+// estimate is the median interval between purchases of the product in
+// purchase-history.json, counted from the last purchase or from when the
+// item was added, whichever is later.
+type pantryStatus struct {
+	pantryItem
+	Expired      bool    `json:"expired,omitempty"`
+	DaysToExpiry *int    `json:"daysToExpiry,omitempty"`
+	BoughtEvery  float64 `json:"boughtEveryDays,omitempty"`
+	RunsOut      string  `json:"runsOut,omitempty"`
+	DaysLeft     *int    `json:"daysLeft,omitempty"`
+	Low          bool    `json:"low"`
+}
+
+// status estimates every item against the purchase frequencies.
+func (p *pantry) status(freqs map[string]*productFrequency, now time.Time) []pantryStatus {
+	today := dateOnly(now)
+	out := make([]pantryStatus, 0, len(p.Items))
+	for _, it := range p.Items {
+		s := pantryStatus{pantryItem: it, Expired: it.expired(today)}
+		if t, err := time.Parse(dateLayout, it.Expires); err == nil {
+			d := int(math.Round(t.Sub(today).Hours() / 24))
+			s.DaysToExpiry = &d
+		}
+		if f := it.frequency(freqs); f != nil {
+			if interval, ok := f.interval(); ok {
+				from := f.last()
+				if added, err := time.Parse(dateLayout, it.Added); err == nil && added.After(from) {
+					from = added
+				}
+				runsOut := from.AddDate(0, 0, int(math.Round(interval)))
+				left := int(math.Round(runsOut.Sub(today).Hours() / 24))
+				s.BoughtEvery = round1(interval)
+				s.RunsOut = runsOut.Format(dateLayout)
+				s.DaysLeft = &left
+			}
+		}
+		s.Low = it.Qty <= 0 || s.Expired || (s.DaysLeft != nil && *s.DaysLeft <= pantryLowDays)
+		out = append(out, s)
+	}
+	return out
+}
+
+// frequency finds the item's purchase record: by product ID, or else the
+// most bought product whose title contains the item's name.
+func (it pantryItem) frequency(freqs map[string]*productFrequency) *productFrequency {
+	if it.ProductID > 0 {
+		if f, ok := freqs[frequencyKey(it.ProductID, "")]; ok {
+			return f
+		}
+	}
+	var best *productFrequency
+	for _, f := range freqs {
+		if it.covers(f.Title, 0) && (best == nil || len(f.Days) > len(best.Days)) {
+			best = f
+		}
+	}
+	return best
+}
+
+// pantryStock answers whether the pantry covers an ingredient or product
+// for plan-week: in stock, not expired and not about to run out.
+type pantryStock []pantryStatus
+
+func loadPantryStock() (pantryStock, error) {
+	p, err := loadPantry()
+	if err != nil || len(p.Items) == 0 {
+		return nil, err
+	}
+	h, err := loadPurchaseHistory()
+	if err != nil {
+		return nil, err
+	}
+	return p.status(purchaseFrequencies(h), time.Now()), nil
+}
+
+func (s pantryStock) covers(name string, id int) bool {
+	for _, it := range s {
+		if !it.Low && it.covers(name, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// lowStaples are the staples to buy again: running low or used up, with a
+// product ID to order.
+func (s pantryStock) lowStaples() []pantryStatus {
+	var out []pantryStatus
+	for _, it := range s {
+		if it.Staple && it.Low && it.ProductID > 0 {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+// baseQty converts kg and l to g and ml so stock in either adds up.
+func baseQty(qty float64, unit string) (float64, string) {
+	switch unit {
+	case "kg":
+		return qty * 1000, "g"
+	case "l":
+		return qty * 1000, "ml"
+	}
+	return qty, unit
+}
+
+// add puts qty more in stock. Amounts only add up in the same unit ("2
+// pakken rijst" and "500 g rijst" do not); a used-up item takes the new
+// unit.
+func (it *pantryItem) add(qty float64, unit string) error {
+	qty, unit = baseQty(qty, unit)
+	oldQty, oldUnit := baseQty(it.Qty, it.Unit)
+	if oldQty <= 0 {
+		oldUnit = unit
+	}
+	if oldUnit != unit {
+		return fmt.Errorf("%s is stocked in %s, not %s", it.Name, unitName(oldUnit), unitName(unit))
+	}
+	it.Qty, it.Unit = oldQty+qty, oldUnit
+	return nil
+}
+
+// use takes qty out of stock, never below zero, in the same unit as the
+// stock.
+func (it *pantryItem) use(qty float64, unit string) error {
+	qty, unit = baseQty(qty, unit)
+	oldQty, oldUnit := baseQty(it.Qty, it.Unit)
+	if oldUnit != unit {
+		return fmt.Errorf("%s is stocked in %s, not %s", it.Name, unitName(oldUnit), unitName(unit))
+	}
+	it.Qty, it.Unit = math.Max(oldQty-qty, 0), oldUnit
+	return nil
+}
+
+// unitName is the unit for messages; no unit is a count.
+func unitName(unit string) string {
+	if unit == "" {
+		return "pieces"
+	}
+	return unit
+}
+
+func runPantry(argv []string) {
+	if len(argv) < 1 {
+		pantryUsage()
+	}
+	args := parseArgs(argv[1:], "staple", "all", "low", "dry-run")
+	p, err := loadPantry()
+	if err != nil {
+		fatal("Load pantry failed: %v", err)
+	}
+	now := time.Now()
+
+	switch argv[0] {
+	case "add":
+		item := pantryItemFromArgs(args)
+		item.Added = now.Format(dateLayout)
+		item.Staple = args.flag("staple", false)
+		if v := args.str("expires", ""); v != "" {
+			if _, err := time.Parse(dateLayout, v); err != nil {
+				fatal("Invalid --expires %q (use YYYY-MM-DD)", v)
+			}
+			item.Expires = v
+		} else if days := args.num("days", 0); days > 0 {
+			item.Expires = now.AddDate(0, 0, days).Format(dateLayout)
+		}
+		i := p.find(item.Name)
+		if item.ProductID > 0 {
+			if j := p.find(strconv.Itoa(item.ProductID)); j >= 0 {
+				i = j
+			}
+		}
+		if i >= 0 {
+			old := &p.Items[i]
+			if err := old.add(item.Qty, item.Unit); err != nil {
+				fatal("%v", err)
+			}
+			old.Added = item.Added
+			old.Staple = old.Staple || item.Staple
+			if item.Expires != "" {
+				old.Expires = item.Expires
+			}
+			item = *old
+		} else {
+			p.Items = append(p.Items, item)
+		}
+		savePantry(p)
+		printJSON(item)
+
+	case "use":
+		ref := strings.Join(args.pos, " ")
+		if ref == "" {
+			pantryUsage()
+		}
+		i := p.find(ref)
+		// Without a unit, the quantity is in the unit of the stock.
+		qty, unit, phrase := 1.0, "", false
+		if i < 0 {
+			// "use 200 g rijst": the quantity is part of the phrase.
+			if it, ok := parser.ParseItem(ref); ok {
+				if i = p.find(it.Name); i >= 0 {
+					qty, unit, phrase = it.Qty, it.Unit, true
+				}
+			}
+		}
+		if i < 0 {
+			fatal("%q is not in the pantry", ref)
+		}
+		it := &p.Items[i]
+		if !phrase {
+			unit = it.Unit
+		}
+		if v := args.str("qty", ""); v != "" {
+			if qty, err = strconv.ParseFloat(strings.ReplaceAll(v, ",", "."), 64); err != nil {
+				fatal("Invalid --qty %q", v)
+			}
+			unit = it.Unit
+		}
+		if args.flag("all", false) {
+			qty, unit = it.Qty, it.Unit
+		}
+		if err := it.use(qty, unit); err != nil {
+			fatal("%v; pass --qty in %s", err, unitName(it.Unit))
+		}
+		result := *it
+		if it.Qty == 0 && !it.Staple {
+			p.Items = slices.Delete(p.Items, i, i+1)
+		}
+		savePantry(p)
+		printJSON(result)
+
+	case "list":
+		h, err := loadPurchaseHistory()
+		if err != nil {
+			fatal("Load purchase history failed: %v", err)
+		}
+		status := p.status(purchaseFrequencies(h), now)
+		if args.flag("low", false) {
+			status = slices.DeleteFunc(status, func(s pantryStatus) bool { return !s.Low })
+		}
+		printJSON(map[string]any{"items": status})
+
+	case "expire":
+		soon := args.num("days", 3)
+		today := dateOnly(now)
+		removed, expiring := []pantryItem{}, []pantryItem{}
+		kept := p.Items[:0]
+		for _, it := range p.Items {
+			if it.expired(today) {
+				removed = append(removed, it)
+				if !it.Staple {
+					continue
+				}
+				it.Qty, it.Expires = 0, ""
+			} else if t, err := time.Parse(dateLayout, it.Expires); err == nil && t.Sub(today).Hours()/24 <= float64(soon) {
+				expiring = append(expiring, it)
+			}
+			kept = append(kept, it)
+		}
+		p.Items = kept
+		if !args.flag("dry-run", false) && len(removed) > 0 {
+			savePantry(p)
+		}
+		printJSON(map[string]any{"removed": removed, "expiringSoon": expiring})
+
+	default:
+		pantryUsage()
+	}
+}
+
+// pantryItemFromArgs reads "add <product-id|phrase> [qty]". A phrase goes
+// through the grocery parser, so "2 pakken rijst" and "500 g gehakt" work,
+// and gets its product ID from --id or product-cache.json. A product ID
+// takes its name from the product index or --name.
+func pantryItemFromArgs(args cmdArgs) pantryItem {
+	if len(args.pos) == 0 {
+		pantryUsage()
+	}
+	var item pantryItem
+	// "2 melk" is a phrase; "8817 2" a product ID and quantity.
+	id, err := strconv.Atoi(args.pos[0])
+	if err == nil && (len(args.pos) == 1 || len(args.pos) == 2 && args.argInt(1, 0) > 0) {
+		item.ProductID, item.Qty = id, float64(args.argInt(1, 1))
+		item.Name = args.str("name", "")
+		if item.Name == "" {
+			if idx, err := loadProductIndex(); err == nil {
+				item.Name = idx.Products[id].Title
+			}
+		}
+		if item.Name == "" {
+			fatal("Product %d is not in product-index.json; pass --name", id)
+		}
+	} else {
+		it, ok := parser.ParseItem(strings.Join(args.pos, " "))
+		if !ok {
+			pantryUsage()
+		}
+		item.Name, item.Qty, item.Unit = it.Name, it.Qty, it.Unit
+		item.ProductID = args.num("id", 0)
+		if item.ProductID == 0 {
+			if cache, err := loadProductCache(); err == nil {
+				item.ProductID, _ = cache.lookup(item.Name)
+			}
+		}
+	}
+	if v := args.str("qty", ""); v != "" {
+		qty, err := strconv.ParseFloat(strings.ReplaceAll(v, ",", "."), 64)
+		if err != nil {
+			fatal("Invalid --qty %q", v)
+		}
+		item.Qty = qty
+	}
+	if u := args.str("unit", ""); u != "" {
+		item.Unit = u
+	}
+	item.Qty, item.Unit = baseQty(item.Qty, item.Unit)
+	return item
+}
+
+func savePantry(p *pantry) {
+	if err := p.save(); err != nil {
+		fatal("Save pantry failed: %v", err)
+	}
+}
+
+func pantryUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: appie-cli pantry add <product-id> [qty] [--name n] | <phrase> [--id n]")
+	fmt.Fprintln(os.Stderr, "                            [--qty n] [--unit u] [--expires YYYY-MM-DD | --days n] [--staple]")
+	fmt.Fprintln(os.Stderr, "       appie-cli pantry use <name|product-id|phrase> [--qty n] [--all]")
+	fmt.Fprintln(os.Stderr, "       appie-cli pantry list [--low]")
+	fmt.Fprintln(os.Stderr, "       appie-cli pantry expire [--days n] [--dry-run]")
+	os.Exit(1)
+}
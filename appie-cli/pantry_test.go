@@ -0,0 +1,107 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPantryItemAdd(t *testing.T) {
+	tests := []struct {
+		stock   pantryItem
+		qty     float64
+		unit    string
+		wantQty float64
+		want    string // unit after adding, "error" for a mismatch
+	}{
+		{pantryItem{Qty: 2, Unit: "pak"}, 1, "pak", 3, "pak"},
+		{pantryItem{Qty: 500, Unit: "g"}, 1, "kg", 1500, "g"},
+		{pantryItem{Qty: 1, Unit: "l"}, 250, "ml", 1250, "ml"},
+		{pantryItem{Qty: 3}, 2, "", 5, ""},
+		{pantryItem{Qty: 2, Unit: "pak"}, 500, "g", 2, "error"},
+		{pantryItem{Qty: 3}, 200, "g", 3, "error"},
+		// A used-up staple takes the unit of what is added.
+		{pantryItem{Qty: 0, Unit: "pak"}, 500, "g", 500, "g"},
+	}
+	for _, tt := range tests {
+		it := tt.stock
+		err := it.add(tt.qty, tt.unit)
+		if (err != nil) != (tt.want == "error") {
+			t.Errorf("%+v.add(%g %s) error = %v", tt.stock, tt.qty, tt.unit, err)
+			continue
+		}
+		if err == nil && (it.Qty != tt.wantQty || it.Unit != tt.want) {
+			t.Errorf("%+v.add(%g %s) = %g %s, want %g %s", tt.stock, tt.qty, tt.unit, it.Qty, it.Unit, tt.wantQty, tt.want)
+		}
+	}
+}
+
+func TestPantryItemUse(t *testing.T) {
+	tests := []struct {
+		stock   pantryItem
+		qty     float64
+		unit    string
+		wantQty float64
+		wantErr bool
+	}{
+		{pantryItem{Qty: 500, Unit: "g"}, 200, "g", 300, false},
+		{pantryItem{Qty: 500, Unit: "g"}, 0.2, "kg", 300, false},
+		{pantryItem{Qty: 2, Unit: "pak"}, 1, "pak", 1, false},
+		{pantryItem{Qty: 2, Unit: "pak"}, 3, "pak", 0, false},
+		{pantryItem{Qty: 2, Unit: "pak"}, 200, "g", 2, true},
+		{pantryItem{Qty: 4}, 200, "g", 4, true},
+	}
+	for _, tt := range tests {
+		it := tt.stock
+		err := it.use(tt.qty, tt.unit)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%+v.use(%g %s) error = %v", tt.stock, tt.qty, tt.unit, err)
+			continue
+		}
+		if it.Qty != tt.wantQty {
+			t.Errorf("%+v.use(%g %s) = %g, want %g", tt.stock, tt.qty, tt.unit, it.Qty, tt.wantQty)
+		}
+	}
+}
+
+func TestPantryStatus(t *testing.T) {
+	now := time.Date(2026, 3, 20, 15, 0, 0, 0, time.UTC)
+	h := &purchaseHistory{ListAdditions: []listAddition{
+		{Date: "2026-02-20", ProductID: 1, Title: "AH Basmati rijst", Quantity: 1},
+		{Date: "2026-03-06", ProductID: 1, Title: "AH Basmati rijst", Quantity: 1},
+		{Date: "2026-03-01", ProductID: 2, Title: "AH Halfvolle melk", Quantity: 2},
+		{Date: "2026-03-08", ProductID: 2, Title: "AH Halfvolle melk", Quantity: 2},
+	}}
+	p := &pantry{Items: []pantryItem{
+		// bought every 14 days, last on 03-06: runs out 03-20
+		{Name: "rijst", ProductID: 1, Qty: 1, Unit: "pak", Added: "2026-03-06"},
+		// found by name; added after the last purchase, so counted from 03-18
+		{Name: "melk", Qty: 1, Added: "2026-03-18"},
+		{Name: "eieren", Qty: 6, Added: "2026-03-10", Expires: "2026-03-19"},
+		{Name: "olijfolie", Qty: 0, Added: "2026-01-01", Staple: true},
+		{Name: "pindakaas", Qty: 1, Added: "2026-03-01", Expires: "2026-06-01"},
+	}}
+	tests := []struct {
+		every    float64
+		daysLeft int // -1 for no estimate
+		expired  bool
+		low      bool
+	}{
+		{14, 0, false, true},
+		{7, 5, false, true},
+		{0, -1, true, true},
+		{0, -1, false, true},
+		{0, -1, false, false},
+	}
+	status := p.status(purchaseFrequencies(h), now)
+	for i, tt := range tests {
+		s := status[i]
+		left := -1
+		if s.DaysLeft != nil {
+			left = *s.DaysLeft
+		}
+		if s.BoughtEvery != tt.every || left != tt.daysLeft || s.Expired != tt.expired || s.Low != tt.low {
+			t.Errorf("%s: every %g, daysLeft %d, expired %v, low %v; want %g, %d, %v, %v",
+				s.Name, s.BoughtEvery, left, s.Expired, s.Low, tt.every, tt.daysLeft, tt.expired, tt.low)
+		}
+	}
+}
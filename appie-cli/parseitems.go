@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/markooms/openclaw-skill-albert-heijn/appie-cli/parser"
+)
+
+// runParseItems parses free-form grocery text from the arguments or stdin.
+// With --batch the output is a batch-add payload: names to resolve, with the
+// number of packages to add.
+func runParseItems(argv []string) {
+	args := parseArgs(argv, "batch")
+	text := strings.Join(args.pos, " ")
+	if text == "" || text == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fatal("Read input failed: %v", err)
+		}
+		text = string(data)
+	}
+	if strings.TrimSpace(text) == "" {
+		fmt.Fprintln(os.Stderr, "Usage: appie-cli parse-items \"twee pakken melk en een netje uien\" [--batch]")
+		os.Exit(1)
+	}
+
+	items := parser.Parse(text)
+	if !args.flag("batch", false) {
+		if items == nil {
+			items = []parser.Item{}
+		}
+		printJSON(items)
+		return
+	}
+	entries := make([]map[string]any, 0, len(items))
+	for _, it := range items {
+		entries = append(entries, map[string]any{"name": it.Name, "qty": it.Packages()})
+	}
+	printJSON(entries)
+}
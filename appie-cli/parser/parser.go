@@ -0,0 +1,272 @@
+// Package parser turns free-form Dutch or English grocery phrases such as
+// "twee pakken melk en een netje uien" into structured items.
+package parser
+
+import (
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Item is one grocery item from a phrase. Qty is in Unit, which is a
+// package (pak, fles, net, dozijn, ...), a measure (g, kg, ml, l) or empty
+// for a plain count. Name is singular, as in product-cache.json.
+type Item struct {
+	Input string  `json:"input"`
+	Name  string  `json:"name"`
+	Qty   float64 `json:"qty"`
+	Unit  string  `json:"unit,omitempty"`
+}
+
+// IsMeasure reports whether Qty is a weight or volume rather than a count.
+func (it Item) IsMeasure() bool {
+	switch it.Unit {
+	case "g", "kg", "ml", "l":
+		return true
+	}
+	return false
+}
+
+// Packages is the number of products to put on the list: the count rounded
+// up, or 1 for a weight or volume.
+func (it Item) Packages() int {
+	if it.IsMeasure() {
+		return 1
+	}
+	return max(int(math.Ceil(it.Qty)), 1)
+}
+
+// numberWords are the number words and quantifiers; articles count as 1
+// only when no other number follows.
+var numberWords = map[string]float64{
+	"één": 1, "eén": 1, "twee": 2, "drie": 3, "vier": 4, "vijf": 5, "zes": 6,
+	"zeven": 7, "acht": 8, "negen": 9, "tien": 10, "elf": 11, "twaalf": 12,
+	"vijftien": 15, "twintig": 20, "paar": 2, "half": 0.5, "halve": 0.5,
+	"anderhalf": 1.5, "anderhalve": 1.5,
+	"one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+	"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+	"fifteen": 15, "twenty": 20, "couple": 2,
+}
+
+var articles = map[string]bool{"een": true, "a": true, "an": true}
+
+// dozens are a unit of 12, usually one box ("twee dozijn eieren"). The
+// English "dozen" is left out: it is also the Dutch plural of doos.
+var dozens = map[string]bool{"dozijn": true}
+
+// idioms are names that start with a number word, so they are never read as
+// a quantity ("half om half gehakt").
+var idioms = [][]string{{"half", "om", "half"}}
+
+func startsWithIdiom(words []string) bool {
+	for _, idiom := range idioms {
+		if len(words) >= len(idiom) && slices.Equal(words[:len(idiom)], idiom) {
+			return true
+		}
+	}
+	return false
+}
+
+// unit is a unit word: its canonical name and the factor to convert to it
+// (1 ons = 100 g).
+type unit struct {
+	name   string
+	factor float64
+}
+
+var units = map[string]unit{}
+
+func init() {
+	for name, words := range map[string][]string{
+		"pak":  {"pak", "pakken", "pakje", "pakjes", "pack", "packs", "carton", "cartons"},
+		"fles": {"fles", "flessen", "flesje", "flesjes", "bottle", "bottles"},
+		"zak":  {"zak", "zakken", "zakje", "zakjes", "bag", "bags"},
+		"net":  {"net", "netten", "netje", "netjes", "nets"},
+		"blik": {"blik", "blikken", "blikje", "blikjes", "can", "cans", "tin", "tins"},
+		"doos": {"doos", "dozen", "doosje", "doosjes", "box", "boxes"},
+		"pot":  {"pot", "potten", "potje", "potjes", "jar", "jars"},
+		"bos":  {"bos", "bossen", "bosje", "bosjes", "bunch", "bunches"},
+		"krop": {"krop", "kroppen", "head", "heads"},
+		"stuk": {"stuk", "stuks", "stukken", "piece", "pieces"},
+		"tros": {"tros", "trossen"},
+		"bak":  {"bak", "bakken", "bakje", "bakjes", "tray", "trays", "punnet", "punnets"},
+		"rol":  {"rol", "rollen", "rolletje", "roll", "rolls"},
+		"g":    {"g", "gr", "gram", "grams", "gramme"},
+		"kg":   {"kg", "kilo", "kilos", "kilo's", "kilogram", "kilograms"},
+		"ml":   {"ml", "milliliter", "millilitre"},
+		"l":    {"l", "liter", "liters", "litre", "litres"},
+	} {
+		for _, w := range words {
+			units[w] = unit{name, 1}
+		}
+	}
+	units["cl"] = unit{"ml", 10}
+	units["ons"] = unit{"g", 100}
+	units["pond"] = unit{"g", 500}
+}
+
+var (
+	decimalComma = regexp.MustCompile(`(\d),(\d)`)
+	separators   = regexp.MustCompile(`(?i)\s*(?:[,;\n]|\s(?:en|and|&|\+)\s)\s*`)
+	attachedUnit = regexp.MustCompile(`^(\d+(?:\.\d+)?)(x|[a-z]+)$`)
+	fillers      = map[string]bool{"graag": true, "alsjeblieft": true, "aub": true, "please": true, "nog": true, "ook": true, "also": true, "some": true, "wat": true}
+)
+
+// Parse splits text on commas, semicolons, newlines and "en"/"and" and
+// parses each part. Parts without a name are dropped.
+func Parse(text string) []Item {
+	text = decimalComma.ReplaceAllString(text, "$1.$2")
+	var items []Item
+	for _, part := range separators.Split(" "+text+" ", -1) {
+		if it, ok := ParseItem(part); ok {
+			items = append(items, it)
+		}
+	}
+	return items
+}
+
+// ParseItem parses one phrase: a quantity (digits or number words), an
+// optional unit and the product name. Without a quantity Qty is 1.
+func ParseItem(phrase string) (Item, bool) {
+	it := Item{Input: strings.TrimSpace(phrase)}
+	var words []string
+	for _, w := range strings.Fields(strings.ToLower(phrase)) {
+		w = strings.Trim(w, ".!?:\"()-•*")
+		if w != "" && !fillers[w] {
+			words = append(words, w)
+		}
+	}
+
+	i, article := 0, false
+quantity:
+	for ; i < len(words); i++ {
+		w := words[i]
+		if startsWithIdiom(words[i:]) {
+			break
+		}
+		if n, err := strconv.ParseFloat(strings.TrimSuffix(w, "x"), 64); err == nil {
+			if it.Qty > 0 && !article {
+				break
+			}
+			it.Qty, article = n, false
+			continue
+		}
+		if m := attachedUnit.FindStringSubmatch(w); m != nil {
+			if u, ok := units[m[2]]; ok && it.Qty == 0 {
+				n, _ := strconv.ParseFloat(m[1], 64)
+				it.Qty, it.Unit = n*u.factor, u.name
+				i++
+				break
+			}
+		}
+		switch {
+		case articles[w]:
+			if it.Qty == 0 {
+				it.Qty, article = 1, true
+			}
+		case dozens[w]:
+			it.Qty, it.Unit = max(it.Qty, 1), "dozijn"
+			i++
+			break quantity
+		case numberWords[w] > 0:
+			if it.Qty > 0 && !article {
+				break quantity
+			}
+			it.Qty, article = numberWords[w], false
+		case w == "x" && it.Qty > 0:
+		default:
+			break quantity
+		}
+	}
+	if it.Qty == 0 {
+		it.Qty = 1
+	}
+	if it.Unit == "" && i < len(words) {
+		if u, ok := units[words[i]]; ok && i+1 < len(words) {
+			it.Qty, it.Unit = it.Qty*u.factor, u.name
+			i++
+		}
+	}
+	if i < len(words) && (words[i] == "van" || words[i] == "of") {
+		i++
+	}
+	if i == len(words) {
+		return it, false
+	}
+	name := words[i:]
+	name[len(name)-1] = Singular(name[len(name)-1])
+	it.Name = strings.Join(name, " ")
+	return it, true
+}
+
+// irregular plurals, and words that only look plural.
+var irregular = map[string]string{
+	"eieren": "ei", "uien": "ui", "peren": "peer", "bladen": "blad",
+	"repen": "reep", "groenten": "groente", "cookies": "cookie",
+	"smoothies": "smoothie", "chicken": "chicken", "linzen": "linzen",
+	"kers": "kers",
+}
+
+// compoundTails are the irregular plurals that also end compounds
+// ("chocoladerepen", "kwarteleieren", "stoofperen"). An open e is not
+// doubled by the regular rule, as it would turn "wortelen" into "worteel".
+var compoundTails = []string{"eieren", "uien", "peren", "bladen", "repen"}
+
+// Singular returns the singular of a Dutch or English noun: "tomaten" ->
+// "tomaat", "kippen" -> "kip", "paprika's" -> "paprika", "onions" -> "onion".
+// Words it does not recognise as plural are returned unchanged.
+func Singular(w string) string {
+	if s, ok := irregular[w]; ok {
+		return s
+	}
+	for _, tail := range compoundTails {
+		if strings.HasSuffix(w, tail) {
+			return strings.TrimSuffix(w, tail) + irregular[tail]
+		}
+	}
+	switch {
+	case strings.HasSuffix(w, "'s"):
+		return strings.TrimSuffix(w, "'s")
+	case len(w) > 4 && strings.HasSuffix(w, "ies"):
+		return strings.TrimSuffix(w, "ies") + "y"
+	case len(w) > 4 && hasAnySuffix(w, "oes", "ches", "shes", "xes"):
+		return strings.TrimSuffix(w, "es")
+	case len(w) > 4 && strings.HasSuffix(w, "en") && !hasAnySuffix(w, "oen", "een"):
+		return dutchStem(strings.TrimSuffix(w, "en"))
+	case len(w) > 3 && strings.HasSuffix(w, "s") && strings.ContainsRune("elrnmgkt", rune(w[len(w)-2])) && !strings.HasSuffix(w, "ees"):
+		return strings.TrimSuffix(w, "s")
+	}
+	return w
+}
+
+func hasAnySuffix(s string, suffixes ...string) bool {
+	for _, x := range suffixes {
+		if strings.HasSuffix(s, x) {
+			return true
+		}
+	}
+	return false
+}
+
+// dutchStem restores the singular from a stem without -en: a doubled final
+// consonant is halved (kipp -> kip), the vowel of an open syllable doubled
+// (tomat -> tomaat) and a final z or v devoiced (kaz -> kaas, druiv -> druif).
+func dutchStem(s string) string {
+	isVowel := func(c byte) bool { return strings.IndexByte("aeiouy", c) >= 0 }
+	n := len(s)
+	switch {
+	case n >= 2 && s[n-1] == s[n-2] && !isVowel(s[n-1]):
+		s = s[:n-1]
+	case n >= 3 && !isVowel(s[n-1]) && strings.IndexByte("aou", s[n-2]) >= 0 && !isVowel(s[n-3]):
+		s = s[:n-1] + s[n-2:]
+	}
+	switch {
+	case strings.HasSuffix(s, "z"):
+		s = strings.TrimSuffix(s, "z") + "s"
+	case strings.HasSuffix(s, "v"):
+		s = strings.TrimSuffix(s, "v") + "f"
+	}
+	return s
+}
@@ -0,0 +1,143 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseItem(t *testing.T) {
+	tests := []struct {
+		phrase string
+		name   string
+		qty    float64
+		unit   string
+	}{
+		// number words
+		{"melk", "melk", 1, ""},
+		{"een komkommer", "komkommer", 1, ""},
+		{"twee pakken melk", "melk", 2, "pak"},
+		{"drie uien", "ui", 3, ""},
+		{"twaalf eieren", "ei", 12, ""},
+		{"een paar bananen", "banaan", 2, ""},
+		{"anderhalve liter melk", "melk", 1.5, "l"},
+		{"halve liter slagroom", "slagroom", 0.5, "l"},
+		{"een halve komkommer", "komkommer", 0.5, ""},
+		{"two bottles of wine", "wine", 2, "fles"},
+		{"3x yoghurt", "yoghurt", 3, ""},
+		{"3 x yoghurt", "yoghurt", 3, ""},
+		{"graag nog 2 broden", "brood", 2, ""},
+		{"drie chocoladerepen", "chocoladereep", 3, ""},
+
+		// units
+		{"500 g gehakt", "gehakt", 500, "g"},
+		{"500g gehakt", "gehakt", 500, "g"},
+		{"2 kilo aardappelen", "aardappel", 2, "kg"},
+		{"drie ons kaas", "kaas", 300, "g"},
+		{"een pond gehakt", "gehakt", 500, "g"},
+		{"75 cl wijn", "wijn", 750, "ml"},
+		{"1.5 l cola", "cola", 1.5, "l"},
+		{"een netje uien", "ui", 1, "net"},
+		{"twee dozen eieren", "ei", 2, "doos"},
+		{"een dozijn eieren", "ei", 1, "dozijn"},
+		{"twee dozijn eieren", "ei", 2, "dozijn"},
+		{"een bosje peterselie", "peterselie", 1, "bos"},
+
+		// plurals
+		{"4 tomaten", "tomaat", 4, ""},
+		{"2 kippen", "kip", 2, ""},
+		{"3 paprika's", "paprika", 3, ""},
+		{"2 druiven", "druif", 2, ""},
+		{"2 kazen", "kaas", 2, ""},
+		{"6 appels", "appel", 6, ""},
+		{"2 peren", "peer", 2, ""},
+		{"3 onions", "onion", 3, ""},
+		{"2 tomatoes", "tomato", 2, ""},
+		{"4 cherries", "cherry", 4, ""},
+		{"2 pompoenen", "pompoen", 2, ""},
+		{"linzen", "linzen", 1, ""},
+
+		// names that start with a number word
+		{"half om half gehakt", "half om half gehakt", 1, ""},
+		{"2 pakken half om half gehakt", "half om half gehakt", 2, "pak"},
+	}
+	for _, tt := range tests {
+		it, ok := ParseItem(tt.phrase)
+		if !ok {
+			t.Errorf("ParseItem(%q) found no name", tt.phrase)
+			continue
+		}
+		if it.Name != tt.name || it.Qty != tt.qty || it.Unit != tt.unit {
+			t.Errorf("ParseItem(%q) = %q %g %q, want %q %g %q", tt.phrase, it.Name, it.Qty, it.Unit, tt.name, tt.qty, tt.unit)
+		}
+	}
+}
+
+func TestParseItemWithoutName(t *testing.T) {
+	for _, phrase := range []string{"", "twee", "graag alsjeblieft", "3x"} {
+		if it, ok := ParseItem(phrase); ok {
+			t.Errorf("ParseItem(%q) = %+v, want no item", phrase, it)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text  string
+		names []string
+	}{
+		{"twee pakken melk en een netje uien", []string{"melk", "ui"}},
+		{"melk, brood; kaas\nboter", []string{"melk", "brood", "kaas", "boter"}},
+		{"milk and 2 onions & bread", []string{"milk", "onion", "bread"}},
+		{"1,5 kilo aardappelen", []string{"aardappel"}},
+		{"half om half gehakt en 2 uien", []string{"half om half gehakt", "ui"}},
+	}
+	for _, tt := range tests {
+		var names []string
+		for _, it := range Parse(tt.text) {
+			names = append(names, it.Name)
+		}
+		if !reflect.DeepEqual(names, tt.names) {
+			t.Errorf("Parse(%q) names = %q, want %q", tt.text, names, tt.names)
+		}
+	}
+	if items := Parse("1,5 kilo aardappelen"); items[0].Qty != 1.5 {
+		t.Errorf("Parse decimal comma: qty %g, want 1.5", items[0].Qty)
+	}
+}
+
+func TestPackages(t *testing.T) {
+	tests := []struct {
+		item Item
+		want int
+	}{
+		{Item{Qty: 2}, 2},
+		{Item{Qty: 0.5}, 1},
+		{Item{Qty: 1.5, Unit: "pak"}, 2},
+		{Item{Qty: 500, Unit: "g"}, 1},
+		{Item{Qty: 1.5, Unit: "l"}, 1},
+	}
+	for _, tt := range tests {
+		if got := tt.item.Packages(); got != tt.want {
+			t.Errorf("%+v.Packages() = %d, want %d", tt.item, got, tt.want)
+		}
+	}
+}
+
+func TestSingular(t *testing.T) {
+	tests := map[string]string{
+		"tomaten": "tomaat", "kippen": "kip", "paprika's": "paprika",
+		"uien": "ui", "eieren": "ei", "druiven": "druif", "kazen": "kaas",
+		"appels": "appel", "bananen": "banaan", "onions": "onion",
+		"berries": "berry", "potatoes": "potato", "peaches": "peach",
+		"melk": "melk", "kaas": "kaas", "pompoen": "pompoen", "thee": "thee",
+		"muesli": "muesli", "chicken": "chicken", "cookies": "cookie",
+		"chocoladerepen": "chocoladereep", "repen": "reep",
+		"kwarteleieren": "kwartelei", "stoofperen": "stoofpeer",
+		"lente-uien": "lente-ui", "wortelen": "wortel",
+	}
+	for in, want := range tests {
+		if got := Singular(in); got != want {
+			t.Errorf("Singular(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
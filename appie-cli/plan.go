@@ -0,0 +1,454 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	appie "github.com/gwillem/appie-go"
+)
+
+// knownCuisines are the Allerhande tag stems used to tell cuisines apart.
+var knownCuisines = []string{
+	"italiaans", "frans", "spaans", "grieks", "hollands", "mexicaans", "amerikaans",
+	"aziatisch", "chinees", "japans", "thais", "indiaas", "indonesisch", "vietnamees",
+	"koreaans", "surinaams", "marokkaans", "turks", "midden-oosters", "libanees",
+}
+
+// alwaysInHouse are ingredients never put on the shopping list.
+var alwaysInHouse = map[string]bool{
+	"water": true, "zout": true, "peper": true, "zwarte peper": true, "zout en peper": true,
+}
+
+// weeklyBasics mirrors weekly-basics.json.
+type weeklyBasics struct {
+	Weekly   []basicItem `json:"weekly"`
+	Biweekly []basicItem `json:"biweekly"`
+}
+
+type basicItem struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Qty  int    `json:"qty"`
+}
+
+// loadWeeklyBasics reads weekly-basics.json; a missing file has no basics.
+func loadWeeklyBasics() (*weeklyBasics, error) {
+	b := &weeklyBasics{}
+	data, err := os.ReadFile(skillPath("weekly-basics.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("parse weekly-basics.json: %w", err)
+	}
+	return b, nil
+}
+
+// weekPlan is a proposed week of meals plus the batch-add payload for it.
+type weekPlan struct {
+	Week             string         `json:"week"`
+	HouseholdSize    int            `json:"householdSize"`
+	Meals            []plannedMeal  `json:"meals"`
+	EstimatedSavings float64        `json:"estimatedSavings"`
+	Shopping         []shoppingItem `json:"shopping"`
+	InPantry         []string       `json:"inPantry,omitempty"`
+	Unresolved       []string       `json:"unresolved,omitempty"`
+}
+
+type plannedMeal struct {
+	recipeSuggestion
+	URL      string `json:"url"`
+	Cuisine  string `json:"cuisine,omitempty"`
+	Packages int    `json:"packages"`
+}
+
+// shoppingItem is a batch-add entry. The other fields are informational;
+// batch-add only reads id, text and qty.
+type shoppingItem struct {
+	ID          int      `json:"id,omitempty"`
+	Text        string   `json:"text,omitempty"`
+	Qty         int      `json:"qty"`
+	Name        string   `json:"name"`
+	For         []string `json:"for,omitempty"`
+	Ingredients []string `json:"ingredients,omitempty"`
+	Bonus       bool     `json:"bonus,omitempty"`
+}
+
+// planOptions tunes plan-week beyond config.json.
+type planOptions struct {
+	Meals      int
+	AvoidWeeks int
+	Candidates int
+	Biweekly   bool
+	Resolve    bool
+	Pantry     bool
+}
+
+// recipeURL links to a recipe on Allerhande.
+func recipeURL(id int, slug string) string {
+	return fmt.Sprintf("https://www.ah.nl/allerhande/recept/R-R%d/%s", id, slug)
+}
+
+// recipeCuisine returns the first cuisine tag of a recipe.
+func recipeCuisine(tags []string) string {
+	for _, c := range knownCuisines {
+		if hasTag(tags, c) {
+			return c
+		}
+	}
+	return ""
+}
+
+// pickMeals greedily picks n recipes. Each pick maximises bonus value plus a
+// bonus for liked cuisines and, with preferences.prefer_seasonal, for
+// seasonal produce, with a penalty for repeating a cuisine already in the
+// plan.
+func pickMeals(suggestions []recipeSuggestion, n int, cfg *skillConfig) []plannedMeal {
+	liked := map[string]bool{}
+	for _, c := range cfg.CuisinePreferences.Liked {
+		liked[strings.ToLower(c)] = true
+	}
+	score := func(s recipeSuggestion, cuisine string, used map[string]int) float64 {
+		v := s.Coverage * 2
+		if cfg.Preferences.PreferBonus {
+			v += s.EstimatedSavings
+		}
+		if cfg.Preferences.PreferSeasonal && s.Seasonal != nil {
+			v += s.Seasonal.Share
+		}
+		if cuisine != "" {
+			if liked[cuisine] {
+				v += 1
+			}
+			v -= 1.5 * float64(used[cuisine])
+		}
+		return v
+	}
+
+	var meals []plannedMeal
+	used := map[string]int{}
+	taken := make([]bool, len(suggestions))
+	for len(meals) < n {
+		best, bestScore := -1, math.Inf(-1)
+		for i, s := range suggestions {
+			if taken[i] {
+				continue
+			}
+			if v := score(s, recipeCuisine(s.Tags), used); v > bestScore {
+				best, bestScore = i, v
+			}
+		}
+		if best < 0 {
+			break
+		}
+		taken[best] = true
+		s := suggestions[best]
+		cuisine := recipeCuisine(s.Tags)
+		used[cuisine]++
+		packages := 1
+		if s.Servings > 0 && cfg.HouseholdSize > s.Servings {
+			packages = int(math.Ceil(float64(cfg.HouseholdSize) / float64(s.Servings)))
+		}
+		meals = append(meals, plannedMeal{recipeSuggestion: s, URL: recipeURL(s.ID, s.Slug), Cuisine: cuisine, Packages: packages})
+	}
+	return meals
+}
+
+// planWeek builds a proposal honouring config.json and meal-history.json.
+func planWeek(ctx context.Context, client *appie.Client, cfg *skillConfig, opts planOptions, filters recipeFilters, safety *safetyFilter) (*weekPlan, error) {
+	history, err := loadMealHistory()
+	if err != nil {
+		return nil, err
+	}
+	recent := history.recentRecipes(time.Now().AddDate(0, 0, -7*opts.AvoidWeeks))
+	rejected := history.rejectedRecipes()
+	filters.ExcludeTags = append(filters.ExcludeTags, cfg.CuisinePreferences.Disliked...)
+
+	queries := append([]string{""}, cfg.CuisinePreferences.Liked...)
+	suggestions, _, err := suggestRecipes(ctx, client, cfg, queries, opts.Candidates, filters, safety)
+	if err != nil {
+		return nil, err
+	}
+	fresh := suggestions[:0]
+	for _, s := range suggestions {
+		if !recent[s.ID] && !rejected[s.ID] {
+			fresh = append(fresh, s)
+		}
+	}
+
+	year, week := time.Now().ISOWeek()
+	plan := &weekPlan{
+		Week:          fmt.Sprintf("%d-W%02d", year, week),
+		HouseholdSize: cfg.HouseholdSize,
+		Meals:         pickMeals(fresh, opts.Meals, cfg),
+	}
+	for _, m := range plan.Meals {
+		plan.EstimatedSavings += m.EstimatedSavings
+	}
+	plan.EstimatedSavings = roundCents(plan.EstimatedSavings)
+
+	if err := plan.buildShopping(ctx, client, cfg, opts, safety); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// buildShopping combines the ingredients of all meals with the weekly basics.
+// Bonus matches are used as-is, butcher items become notes, and other
+// ingredients are resolved via product-cache.json and search. A product used
+// by several meals is bought once, for the meal that needs the most packages
+// (one pack of garlic covers the week); the for field lists every meal.
+// Weekly basics follow predictPurchases where it is confident, the
+// weekly/biweekly split otherwise, and other products predicted with
+// confidence are added when due. With opts.Pantry, ingredients and basics
+// in stock in pantry.json are left out (listed under inPantry), and pantry
+// staples running low are added.
+func (p *weekPlan) buildShopping(ctx context.Context, client *appie.Client, cfg *skillConfig, opts planOptions, safety *safetyFilter) error {
+	cache, err := loadProductCache()
+	if err != nil {
+		return err
+	}
+	var stock pantryStock
+	if opts.Pantry {
+		if stock, err = loadPantryStock(); err != nil {
+			return err
+		}
+	}
+	inPantry := func(name string, id int) bool {
+		if !stock.covers(name, id) {
+			return false
+		}
+		if !slices.Contains(p.InPantry, name) {
+			p.InPantry = append(p.InPantry, name)
+		}
+		return true
+	}
+	resolver := &productResolver{client: client, cache: cache, safety: safety}
+	butcher := newButcherRules(cfg.ButcherItems)
+
+	byKey := map[string]*shoppingItem{}
+	var order []string
+	put := func(key string, item shoppingItem, combine func(a, b int) int) {
+		if existing, ok := byKey[key]; ok {
+			existing.Qty = combine(existing.Qty, item.Qty)
+			existing.For = append(existing.For, item.For...)
+			existing.Ingredients = append(existing.Ingredients, item.Ingredients...)
+			return
+		}
+		byKey[key] = &item
+		order = append(order, key)
+	}
+	add := func(key string, item shoppingItem) {
+		put(key, item, func(a, b int) int { return a + b })
+	}
+	// Ingredients merge per product: the meals share the packages.
+	addIngredient := func(key string, item shoppingItem) {
+		put(key, item, func(a, b int) int { return max(a, b) })
+	}
+
+	for _, m := range p.Meals {
+		bonusByIngredient := map[string]bonusMatch{}
+		for _, bm := range m.Matches {
+			bonusByIngredient[bm.Ingredient] = bm
+		}
+		for _, ing := range m.details.Ingredients {
+			name := ing.name()
+			if alwaysInHouse[strings.ToLower(name)] || inPantry(name, 0) {
+				continue
+			}
+			forMeal := []string{m.Title}
+			ingredient := []string{name}
+			if _, ok := butcher.match(name, ""); ok {
+				add("text:"+name, shoppingItem{Text: fmt.Sprintf("%s%s (voor %s)", butcherNotePrefix, name, m.Title), Qty: 1, Name: name, For: forMeal})
+				continue
+			}
+			if bm, ok := bonusByIngredient[name]; ok {
+				if inPantry(name, bm.ProductID) {
+					continue
+				}
+				addIngredient(fmt.Sprintf("id:%d", bm.ProductID), shoppingItem{ID: bm.ProductID, Qty: m.Packages, Name: bm.Title, For: forMeal, Ingredients: ingredient, Bonus: true})
+				continue
+			}
+			if !opts.Resolve {
+				add("text:"+name, shoppingItem{Text: name, Qty: 1, Name: name, For: forMeal})
+				continue
+			}
+			prod, err := resolver.resolve(ctx, name)
+			if err != nil {
+				p.Unresolved = append(p.Unresolved, name)
+				add("text:"+name, shoppingItem{Text: name, Qty: 1, Name: name, For: forMeal})
+				continue
+			}
+			if inPantry(name, prod.ID) {
+				continue
+			}
+			title := prod.Title
+			if title == "" {
+				title = name
+			}
+			addIngredient(fmt.Sprintf("id:%d", prod.ID), shoppingItem{ID: prod.ID, Qty: m.Packages, Name: title, For: forMeal, Ingredients: ingredient})
+		}
+	}
+
+	basics, err := loadWeeklyBasics()
+	if err != nil {
+		return err
+	}
+	preds, err := loadPredictions(time.Now(), 7, false)
+	if err != nil {
+		return err
+	}
+	trusted := map[int]prediction{}
+	for _, pr := range preds {
+		if pr.ProductID > 0 && pr.Confidence >= predictTrust {
+			trusted[pr.ProductID] = pr
+		}
+	}
+	// A basic with a confident prediction is bought when it is due; the
+	// weekly/biweekly split only decides for the others.
+	addBasic := func(b basicItem, static bool) {
+		if b.ID <= 0 || inPantry(b.Name, b.ID) {
+			return
+		}
+		label := "weekly basics"
+		if pr, ok := trusted[b.ID]; ok {
+			if !pr.Due {
+				return
+			}
+			label = fmt.Sprintf("basics, due %s", pr.DueDate)
+		} else if !static {
+			return
+		}
+		add(fmt.Sprintf("id:%d", b.ID), shoppingItem{ID: b.ID, Qty: max(b.Qty, 1), Name: b.Name, For: []string{label}})
+	}
+	for _, b := range basics.Weekly {
+		addBasic(b, true)
+	}
+	for _, b := range basics.Biweekly {
+		addBasic(b, opts.Biweekly)
+	}
+	// Other products bought regularly are added when due, unless a meal or
+	// the basics already put them on the list.
+	for _, pr := range preds {
+		if pr.ProductID <= 0 || pr.Confidence < predictTrust || !pr.Due {
+			continue
+		}
+		key := fmt.Sprintf("id:%d", pr.ProductID)
+		if _, ok := byKey[key]; ok || inPantry(pr.Title, pr.ProductID) {
+			continue
+		}
+		add(key, shoppingItem{ID: pr.ProductID, Qty: pr.Qty, Name: pr.Title, For: []string{"due " + pr.DueDate}})
+	}
+	for _, it := range stock.lowStaples() {
+		key := fmt.Sprintf("id:%d", it.ProductID)
+		if _, ok := byKey[key]; !ok {
+			add(key, shoppingItem{ID: it.ProductID, Qty: 1, Name: it.Name, For: []string{"pantry"}})
+		}
+	}
+
+	for _, key := range order {
+		p.Shopping = append(p.Shopping, *byKey[key])
+	}
+	sort.SliceStable(p.Shopping, func(i, j int) bool {
+		return p.Shopping[i].ID != 0 && p.Shopping[j].ID == 0
+	})
+	return nil
+}
+
+// markdown renders the proposal for chat.
+func (p *weekPlan) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Weekmenu %s\n\n", p.Week)
+	for i, m := range p.Meals {
+		fmt.Fprintf(&b, "%d. **[%s](%s)**", i+1, m.Title, m.URL)
+		if m.CookTime > 0 {
+			fmt.Fprintf(&b, " — %d min", m.CookTime)
+		}
+		if m.Cuisine != "" {
+			fmt.Fprintf(&b, " · %s", m.Cuisine)
+		}
+		b.WriteString("\n")
+		for _, bm := range m.Matches {
+			fmt.Fprintf(&b, "   - 🏷️ %s: %s", bm.Ingredient, bm.Title)
+			if bm.BonusMechanism != "" {
+				fmt.Fprintf(&b, " (%s)", bm.BonusMechanism)
+			}
+			b.WriteString("\n")
+		}
+	}
+	if p.EstimatedSavings > 0 {
+		fmt.Fprintf(&b, "\nGeschat bonusvoordeel: €%.2f\n", p.EstimatedSavings)
+	}
+
+	b.WriteString("\n## Boodschappen\n\n")
+	for _, s := range p.Shopping {
+		label := s.Name
+		if s.Text != "" {
+			label = s.Text
+		}
+		fmt.Fprintf(&b, "- %d× %s", s.Qty, label)
+		if s.Bonus {
+			b.WriteString(" 🏷️")
+		}
+		b.WriteString("\n")
+	}
+	if len(p.InPantry) > 0 {
+		fmt.Fprintf(&b, "\nAl in huis (pantry): %s\n", strings.Join(p.InPantry, ", "))
+	}
+	if len(p.Unresolved) > 0 {
+		fmt.Fprintf(&b, "\nNiet gevonden (als vrije tekst toegevoegd): %s\n", strings.Join(p.Unresolved, ", "))
+	}
+	return b.String()
+}
+
+func runPlanWeek(ctx context.Context, configPath string, argv []string) {
+	args := parseArgs(argv, "safe", "biweekly", "resolve", "markdown", "seasonal", "pantry")
+	client := mustAuth(ctx, configPath)
+	cfg := mustSkillConfig()
+	safety := newSafetyFilter(cfg)
+	if !args.flag("safe", true) {
+		safety = &safetyFilter{}
+	}
+	opts := planOptions{
+		Meals:      args.num("meals", cfg.MealsPerWeek),
+		AvoidWeeks: args.num("avoid-weeks", 4),
+		Candidates: args.num("candidates", 30),
+		Biweekly:   args.flag("biweekly", false),
+		Resolve:    args.flag("resolve", true),
+		Pantry:     args.flag("pantry", true),
+	}
+
+	plan, err := planWeek(ctx, client, cfg, opts, recipeFiltersFromArgs(args, cfg), safety)
+	if err != nil {
+		fatal("Plan week failed: %v", err)
+	}
+	if len(plan.Meals) < opts.Meals {
+		fmt.Fprintf(os.Stderr, "Only found %d of %d meals; try --candidates or --max-time 0\n", len(plan.Meals), opts.Meals)
+	}
+
+	if prefix := args.str("out", ""); prefix != "" {
+		data, _ := json.MarshalIndent(plan, "", "  ")
+		if err := os.WriteFile(prefix+".json", append(data, '\n'), 0o644); err != nil {
+			fatal("Write plan failed: %v", err)
+		}
+		if err := os.WriteFile(prefix+".md", []byte(plan.markdown()), 0o644); err != nil {
+			fatal("Write plan failed: %v", err)
+		}
+	}
+	if args.flag("markdown", false) {
+		fmt.Print(plan.markdown())
+		return
+	}
+	printJSON(plan)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func testMeal(title string, packages int, matches []bonusMatch, ingredients ...string) plannedMeal {
+	d := &recipeDetails{Title: title}
+	for _, name := range ingredients {
+		d.Ingredients = append(d.Ingredients, recipeIngredient{Name: recipeNoun{Singular: name}})
+	}
+	m := plannedMeal{Packages: packages}
+	m.Title, m.Matches, m.details = title, matches, d
+	return m
+}
+
+func TestBuildShopping(t *testing.T) {
+	t.Setenv("APPIE_SKILL_DIR", t.TempDir())
+	knoflook := []bonusMatch{{Ingredient: "knoflook", ProductID: 1, Title: "AH Knoflook"}}
+	p := &weekPlan{Meals: []plannedMeal{
+		testMeal("Curry", 1, knoflook, "knoflook", "kip", "kippenbouillon"),
+		testMeal("Pasta", 2, knoflook, "knoflook", "gehakt", "gehaktkruiden"),
+		testMeal("Soep", 1, knoflook, "knoflook", "kippensoep", "zout"),
+	}}
+	cfg := &skillConfig{ButcherItems: []string{"kip", "gehakt"}}
+	if err := p.buildShopping(context.Background(), nil, cfg, planOptions{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		name string
+		id   int
+		qty  int
+		text string
+		meal int
+	}{
+		// One product for three meals: the largest per-meal quantity.
+		{"AH Knoflook", 1, 2, "", 3},
+		{"kip", 0, 1, butcherNotePrefix + "kip (voor Curry)", 1},
+		{"kippenbouillon", 0, 1, "kippenbouillon", 1},
+		{"gehakt", 0, 1, butcherNotePrefix + "gehakt (voor Pasta)", 1},
+		{"gehaktkruiden", 0, 1, "gehaktkruiden", 1},
+		{"kippensoep", 0, 1, "kippensoep", 1},
+	}
+	if len(p.Shopping) != len(want) {
+		t.Fatalf("shopping = %+v, want %d items", p.Shopping, len(want))
+	}
+	for i, w := range want {
+		got := p.Shopping[i]
+		if got.Name != w.name || got.ID != w.id || got.Qty != w.qty || got.Text != w.text || len(got.For) != w.meal {
+			t.Errorf("shopping[%d] = %+v, want %+v", i, got, w)
+		}
+	}
+}
+
+func TestBuildShoppingPredictions(t *testing.T) {
+	t.Setenv("APPIE_SKILL_DIR", t.TempDir())
+	now := time.Now()
+	h := &purchaseHistory{}
+	// Bought weekly, last a week ago: due. Product 3 is bought every two
+	// weeks, last yesterday, so not due; product 4 is due but a meal needs it.
+	bought := func(id int, title string, every, lastDaysAgo int) {
+		for n := 4; n >= 0; n-- {
+			date := now.AddDate(0, 0, -lastDaysAgo-every*n).Format(dateLayout)
+			h.ListAdditions = append(h.ListAdditions, listAddition{Date: date, ProductID: id, Title: title, Quantity: 1})
+		}
+	}
+	bought(1, "AH Halfvolle melk", 7, 7)
+	bought(2, "AH Bananen", 7, 7)
+	bought(3, "AH Volkoren brood", 14, 1)
+	bought(4, "AH Knoflook", 7, 7)
+	if err := h.save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(skillPath("weekly-basics.json"), []byte(`{"weekly": [{"id": 2, "name": "Bananen", "qty": 2}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	knoflook := []bonusMatch{{Ingredient: "knoflook", ProductID: 4, Title: "AH Knoflook"}}
+	p := &weekPlan{Meals: []plannedMeal{testMeal("Curry", 1, knoflook, "knoflook")}}
+	if err := p.buildShopping(context.Background(), nil, &skillConfig{}, planOptions{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	due := "due " + now.Format(dateLayout)
+	want := []struct {
+		id    int
+		qty   int
+		label string
+	}{
+		{4, 1, "Curry"},
+		{2, 2, "basics, " + due},
+		{1, 1, due},
+	}
+	if len(p.Shopping) != len(want) {
+		t.Fatalf("shopping = %+v, want %d items", p.Shopping, len(want))
+	}
+	for i, w := range want {
+		got := p.Shopping[i]
+		if got.ID != w.id || got.Qty != w.qty || len(got.For) != 1 || got.For[0] != w.label {
+			t.Errorf("shopping[%d] = %+v, want %+v", i, got, w)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// prediction is when a product is expected to be bought again: its last
+// purchase plus the median interval between purchases.
+type prediction struct {
+	ProductID    int     `json:"productId,omitempty"`
+	Title        string  `json:"title"`
+	Qty          int     `json:"qty"`
+	Purchases    int     `json:"purchases"`
+	IntervalDays float64 `json:"intervalDays"`
+	LastBought   string  `json:"lastBought"`
+	DueDate      string  `json:"dueDate"`
+	DaysUntilDue int     `json:"daysUntilDue"`
+	Due          bool    `json:"due"`
+	Confidence   float64 `json:"confidence"`
+	InPantry     bool    `json:"inPantry,omitempty"`
+}
+
+// predictTrust is the confidence from which plan-week follows a prediction
+// for a weekly basic instead of the weekly/biweekly split.
+const predictTrust = 0.5
+
+// predictPurchases predicts every product bought at least twice. A product
+// is due when its due date falls within horizon days of now, or has passed.
+func predictPurchases(freqs map[string]*productFrequency, now time.Time, horizon int) []prediction {
+	today := dateOnly(now)
+	var out []prediction
+	for _, f := range freqs {
+		interval, ok := f.interval()
+		if !ok || interval <= 0 {
+			continue
+		}
+		last := f.last()
+		due := last.AddDate(0, 0, int(math.Round(interval)))
+		until := int(math.Round(due.Sub(today).Hours() / 24))
+		out = append(out, prediction{
+			ProductID:    f.ProductID,
+			Title:        f.Title,
+			Qty:          f.typicalQty(),
+			Purchases:    len(f.Days),
+			IntervalDays: round1(interval),
+			LastBought:   last.Format(dateLayout),
+			DueDate:      due.Format(dateLayout),
+			DaysUntilDue: until,
+			Due:          until <= horizon,
+			Confidence:   f.confidence(interval, until),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Confidence != out[j].Confidence {
+			return out[i].Confidence > out[j].Confidence
+		}
+		return out[i].Title < out[j].Title
+	})
+	return out
+}
+
+// confidence rates a prediction from 0 to 1. Regular intervals (a small
+// median deviation from the median interval, so one skipped week does not
+// count much) and more purchases raise it: one interval gives at most 0.45,
+// five or more up to 1. A product overdue by more than two intervals is
+// probably no longer bought, which halves it.
+func (f *productFrequency) confidence(interval float64, daysUntilDue int) float64 {
+	gaps := f.gaps()
+	deviations := make([]float64, len(gaps))
+	for i, g := range gaps {
+		deviations[i] = math.Abs(g - interval)
+	}
+	regularity := math.Max(0, 1-median(deviations)/interval)
+	c := regularity * math.Sqrt(math.Min(float64(len(gaps)), 5)/5)
+	if float64(-daysUntilDue) > 2*interval {
+		c /= 2
+	}
+	return roundCents(c)
+}
+
+// typicalQty is the median quantity per purchase.
+func (f *productFrequency) typicalQty() int {
+	qty := make([]int, len(f.Days))
+	for i, d := range f.Days {
+		qty[i] = d.Qty
+	}
+	sort.Ints(qty)
+	return max(qty[len(qty)/2], 1)
+}
+
+// loadPredictions predicts from purchase-history.json and marks products the
+// pantry covers.
+func loadPredictions(now time.Time, horizon int, usePantry bool) ([]prediction, error) {
+	h, err := loadPurchaseHistory()
+	if err != nil {
+		return nil, err
+	}
+	preds := predictPurchases(purchaseFrequencies(h), now, horizon)
+	if usePantry {
+		stock, err := loadPantryStock()
+		if err != nil {
+			return nil, err
+		}
+		for i, p := range preds {
+			preds[i].InPantry = stock.covers(p.Title, p.ProductID)
+		}
+	}
+	return preds, nil
+}
+
+func runPredict(argv []string) {
+	args := parseArgs(argv, "all", "batch", "pantry")
+	horizon := args.num("days", 7)
+	minConfidence := 0.3
+	if v := args.str("min-confidence", ""); v != "" {
+		var err error
+		if minConfidence, err = strconv.ParseFloat(v, 64); err != nil {
+			fatal("Invalid --min-confidence %q", v)
+		}
+	}
+	preds, err := loadPredictions(time.Now(), horizon, args.flag("pantry", true))
+	if err != nil {
+		fatal("Predict failed: %v", err)
+	}
+	if len(preds) == 0 {
+		fmt.Fprintln(os.Stderr, "No product was bought twice yet; import receipts (receipts import) or add lists with batch-add first")
+	}
+
+	// With --all every prediction is listed; due tells them apart.
+	var due []prediction
+	for _, p := range preds {
+		if args.flag("all", false) || p.Due && !p.InPantry && p.Confidence >= minConfidence {
+			due = append(due, p)
+		}
+	}
+	if args.flag("batch", false) {
+		// Receipt lines without a product ID are resolved by name.
+		items := make([]shoppingItem, 0, len(due))
+		for _, p := range due {
+			items = append(items, shoppingItem{ID: p.ProductID, Qty: p.Qty, Name: p.Title, For: []string{"due " + p.DueDate}})
+		}
+		printJSON(items)
+		return
+	}
+	if due == nil {
+		due = []prediction{}
+	}
+	printJSON(map[string]any{
+		"generated":     time.Now().Format(dateLayout),
+		"horizonDays":   horizon,
+		"minConfidence": minConfidence,
+		"items":         due,
+	})
+}
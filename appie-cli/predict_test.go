@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// boughtAfter builds a purchase record from the gaps between purchases.
+func boughtAfter(gaps ...int) *productFrequency {
+	day := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	f := &productFrequency{Days: []purchaseDay{{day, 1}}}
+	for _, g := range gaps {
+		day = day.AddDate(0, 0, g)
+		f.Days = append(f.Days, purchaseDay{day, 1})
+	}
+	return f
+}
+
+func TestConfidence(t *testing.T) {
+	tests := []struct {
+		name  string
+		f     *productFrequency
+		until int
+		want  float64
+	}{
+		{"weekly", boughtAfter(7, 7, 7, 7, 7), 0, 1},
+		{"bought twice", boughtAfter(7), 0, 0.45},
+		{"one skipped week", boughtAfter(7, 7, 14, 7), 0, 0.89},
+		{"irregular", boughtAfter(3, 10, 5, 12), 0, 0.48},
+		{"overdue by two intervals", boughtAfter(7, 7, 7, 7, 7), -15, 0.5},
+	}
+	for _, tt := range tests {
+		interval, _ := tt.f.interval()
+		if got := tt.f.confidence(interval, tt.until); got != tt.want {
+			t.Errorf("%s: confidence = %g, want %g", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTypicalQty(t *testing.T) {
+	tests := []struct {
+		qty  []int
+		want int
+	}{
+		{[]int{1, 2, 2}, 2},
+		{[]int{3, 1}, 3},
+		{[]int{2, 6, 1, 2, 2}, 2},
+		{[]int{0, 0}, 1},
+	}
+	for _, tt := range tests {
+		f := &productFrequency{}
+		for _, q := range tt.qty {
+			f.Days = append(f.Days, purchaseDay{Qty: q})
+		}
+		if got := f.typicalQty(); got != tt.want {
+			t.Errorf("typicalQty(%v) = %d, want %d", tt.qty, got, tt.want)
+		}
+	}
+}
+
+func TestPredictPurchases(t *testing.T) {
+	now := time.Date(2026, 2, 9, 18, 0, 0, 0, time.UTC)
+	freqs := map[string]*productFrequency{
+		"weekly":   boughtAfter(7, 7, 7, 7), // last 02-02, due 02-09
+		"biweekly": boughtAfter(14, 14),     // last 02-02, due 02-16
+		"once":     boughtAfter(),
+	}
+	freqs["weekly"].Title, freqs["biweekly"].Title = "melk", "koffie"
+	preds := predictPurchases(freqs, now, 3)
+	if len(preds) != 2 {
+		t.Fatalf("predictions = %+v, want 2", preds)
+	}
+	tests := []struct {
+		title string
+		due   string
+		until int
+		isDue bool
+	}{
+		{"melk", "2026-02-09", 0, true},
+		{"koffie", "2026-02-16", 7, false},
+	}
+	for i, tt := range tests {
+		p := preds[i]
+		if p.Title != tt.title || p.DueDate != tt.due || p.DaysUntilDue != tt.until || p.Due != tt.isDue {
+			t.Errorf("preds[%d] = %+v, want %s due %s in %d days (due %v)", i, p, tt.title, tt.due, tt.until, tt.isDue)
+		}
+	}
+}
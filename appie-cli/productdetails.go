@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	appie "github.com/gwillem/appie-go"
+)
+
+// productDetails is the typed view of the product detail endpoint: the
+// product card plus the food information (nutrition, allergens,
+// ingredients) from its GS1 trade item.
+type productDetails struct {
+	appie.Product
+	NutritionBasis string             `json:"nutritionBasis,omitempty"`
+	Nutrition      map[string]float64 `json:"nutrition,omitempty"`
+	Contains       []string           `json:"contains"`
+	MayContain     []string           `json:"mayContain"`
+	Ingredients    string             `json:"ingredients,omitempty"`
+	Labels         []string           `json:"labels,omitempty"`
+}
+
+// flexNumber decodes the trade item's quantities, which come as numbers or
+// strings like "0,5" and "<0.1".
+type flexNumber float64
+
+func (n *flexNumber) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	s = strings.ReplaceAll(strings.TrimLeft(s, "<>~ "), ",", ".")
+	if s == "" || s == "null" {
+		*n = 0
+		return nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("quantity %q: %w", s, err)
+	}
+	*n = flexNumber(f)
+	return nil
+}
+
+type firCode struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+}
+
+type firQuantity struct {
+	Value flexNumber `json:"value"`
+	Unit  firCode    `json:"measurementUnitCode"`
+}
+
+// firResponse is the part of /mobile-services/product/detail/v4/fir/{id}
+// this CLI reads.
+type firResponse struct {
+	ProductCard struct {
+		WebshopID            int      `json:"webshopId"`
+		Title                string   `json:"title"`
+		Brand                string   `json:"brand"`
+		SalesUnitSize        string   `json:"salesUnitSize"`
+		UnitPriceDescription string   `json:"unitPriceDescription"`
+		CurrentPrice         float64  `json:"currentPrice"`
+		PriceBeforeBonus     float64  `json:"priceBeforeBonus"`
+		IsBonus              bool     `json:"isBonus"`
+		BonusMechanism       string   `json:"bonusMechanism"`
+		MainCategory         string   `json:"mainCategory"`
+		SubCategory          string   `json:"subCategory"`
+		NutriScore           string   `json:"nutriscore"`
+		AvailableOnline      bool     `json:"availableOnline"`
+		IsOrderable          bool     `json:"isOrderable"`
+		PropertyIcons        []string `json:"propertyIcons"`
+	} `json:"productCard"`
+	TradeItem struct {
+		Nutrition []struct {
+			Headers []struct {
+				Basis   firQuantity `json:"nutrientBasisQuantity"`
+				Details []struct {
+					Type     firCode       `json:"nutrientTypeCode"`
+					Quantity []firQuantity `json:"quantityContained"`
+				} `json:"nutrientDetail"`
+			} `json:"nutrientHeaders"`
+		} `json:"nutritionalInformation"`
+		Allergens []struct {
+			Items []struct {
+				Type  firCode `json:"typeCode"`
+				Level firCode `json:"levelOfContainmentCode"`
+			} `json:"items"`
+		} `json:"allergenInformation"`
+		Ingredients []struct {
+			Statement string `json:"statement"`
+		} `json:"ingredientInformation"`
+		IngredientStatement string `json:"ingredientStatement"`
+	} `json:"tradeItem"`
+}
+
+// nutrientNames maps GS1 nutrient type codes to the names used in output.
+var nutrientNames = map[string]string{
+	"FAT":    "fat",
+	"FASAT":  "saturated_fat",
+	"CHOAVL": "carbohydrates",
+	"SUGAR-": "sugars",
+	"SUGAR":  "sugars",
+	"FIBTG":  "fibre",
+	"PRO-":   "protein",
+	"SALTEQ": "salt",
+	"NA":     "sodium",
+}
+
+// gs1Allergens maps GS1 allergen type codes to allergenTerms keys, so they
+// line up with the allergies in config.json.
+var gs1Allergens = map[string]string{
+	"AW": "gluten",
+	"AM": "lactose",
+	"AN": "noten",
+	"AP": "pinda",
+	"AE": "ei",
+	"AF": "vis",
+	"AC": "schaaldieren",
+	"UM": "weekdieren",
+	"AY": "soja",
+	"BC": "selderij",
+	"BM": "mosterd",
+	"AS": "sesam",
+	"NL": "lupine",
+	"AU": "sulfiet",
+}
+
+// dietaryIcons are the property icons worth reporting as labels.
+var dietaryIcons = []string{"vegan", "vegetarisch", "biologisch", "glutenvrij", "lactosevrij", "suikervrij"}
+
+// details turns the response into productDetails. Energy is reported in
+// kcal and kJ; nutrients in other units than grams (vitamins) are skipped.
+func (r *firResponse) details() *productDetails {
+	c := r.ProductCard
+	price := c.CurrentPrice
+	if price == 0 {
+		price = c.PriceBeforeBonus
+	}
+	d := &productDetails{
+		Product: appie.Product{
+			ID:                   c.WebshopID,
+			WebshopID:            strconv.Itoa(c.WebshopID),
+			Title:                c.Title,
+			Brand:                c.Brand,
+			Category:             c.MainCategory,
+			SubCategory:          c.SubCategory,
+			Price:                appie.Price{Now: price, Was: c.PriceBeforeBonus},
+			NutriScore:           c.NutriScore,
+			IsBonus:              c.IsBonus,
+			BonusMechanism:       c.BonusMechanism,
+			IsAvailable:          c.AvailableOnline,
+			IsOrderable:          c.IsOrderable,
+			UnitSize:             c.SalesUnitSize,
+			UnitPriceDescription: c.UnitPriceDescription,
+			PropertyIcons:        c.PropertyIcons,
+		},
+		Contains:   []string{},
+		MayContain: []string{},
+	}
+
+	for _, info := range r.TradeItem.Nutrition {
+		for _, h := range info.Headers {
+			if d.Nutrition != nil {
+				break // the first header is per 100 g/ml, later ones per portion
+			}
+			d.Nutrition = map[string]float64{}
+			d.NutritionBasis = strings.TrimSpace(fmt.Sprintf("%g %s", float64(h.Basis.Value), unitLabel(h.Basis.Unit)))
+			for _, n := range h.Details {
+				for _, q := range n.Quantity {
+					switch unit := strings.ToUpper(q.Unit.Value); {
+					case strings.HasPrefix(n.Type.Value, "ENER") && (unit == "E14" || unit == "KCAL"):
+						d.Nutrition["energy_kcal"] = float64(q.Value)
+					case strings.HasPrefix(n.Type.Value, "ENER") && (unit == "KJO" || unit == "KJ"):
+						d.Nutrition["energy_kj"] = float64(q.Value)
+					case unit == "GRM" || unit == "G":
+						if name, ok := nutrientNames[n.Type.Value]; ok {
+							d.Nutrition[name] = float64(q.Value)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, info := range r.TradeItem.Allergens {
+		for _, it := range info.Items {
+			name, ok := gs1Allergens[it.Type.Value]
+			if !ok {
+				name = strings.ToLower(it.Type.Label)
+			}
+			if name == "" || seen[name+it.Level.Value] {
+				continue
+			}
+			seen[name+it.Level.Value] = true
+			switch it.Level.Value {
+			case "CONTAINS":
+				d.Contains = append(d.Contains, name)
+			case "MAY_CONTAIN":
+				d.MayContain = append(d.MayContain, name)
+			}
+		}
+	}
+	sort.Strings(d.Contains)
+	sort.Strings(d.MayContain)
+
+	d.Ingredients = r.TradeItem.IngredientStatement
+	for _, ing := range r.TradeItem.Ingredients {
+		if d.Ingredients == "" {
+			d.Ingredients = strings.TrimSpace(ing.Statement)
+		}
+	}
+
+	for _, icon := range c.PropertyIcons {
+		for _, label := range dietaryIcons {
+			if strings.Contains(strings.ToLower(icon), label) {
+				d.Labels = append(d.Labels, label)
+			}
+		}
+	}
+	return d
+}
+
+func unitLabel(c firCode) string {
+	switch strings.ToUpper(c.Value) {
+	case "GRM":
+		return "g"
+	case "MLT":
+		return "ml"
+	}
+	return strings.ToLower(c.Label)
+}
+
+// getProductDetails fetches the product detail endpoint with its food
+// information, which GetProduct drops.
+func getProductDetails(ctx context.Context, client *appie.Client, id int) (*productDetails, error) {
+	raw, err := getREST(ctx, client, fmt.Sprintf("/mobile-services/product/detail/v4/fir/%d", id))
+	if err != nil {
+		return nil, err
+	}
+	var r firResponse
+	if err := json.Unmarshal(raw, &r); err != nil {
+		return nil, fmt.Errorf("parse error: %w", err)
+	}
+	if r.ProductCard.WebshopID == 0 {
+		r.ProductCard.WebshopID = id
+	}
+	return r.details(), nil
+}
+
+// healthLimits are the "high" thresholds per 100 g of the UK traffic light
+// label, which Dutch dietitians use as well.
+var healthLimits = map[string]float64{
+	"sugars":        22.5,
+	"saturated_fat": 5,
+	"salt":          1.5,
+	"fat":           17.5,
+}
+
+// healthWarnings lists what makes the product a poor fit for
+// preferences.healthy: a Nutri-Score of D or E and nutrients over
+// healthLimits.
+func (d *productDetails) healthWarnings() []string {
+	var warnings []string
+	if ns := strings.ToUpper(d.NutriScore); ns == "D" || ns == "E" {
+		warnings = append(warnings, "Nutri-Score "+ns)
+	}
+	names := make([]string, 0, len(healthLimits))
+	for name := range healthLimits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if v := d.Nutrition[name]; v > healthLimits[name] {
+			warnings = append(warnings, fmt.Sprintf("high %s: %gg per %s", strings.ReplaceAll(name, "_", " "), v, d.NutritionBasis))
+		}
+	}
+	return warnings
+}
+
+// runProductDetails prints the typed product details with the conflicts
+// with config.json's allergies and dislikes, and health warnings when
+// preferences.healthy is set.
+func runProductDetails(ctx context.Context, client *appie.Client, id int) {
+	d, err := getProductDetails(ctx, client, id)
+	if err != nil {
+		fatal("Get product details failed: %v", err)
+	}
+	recordSeen("product", d.Product)
+	cfg := mustSkillConfig()
+	conflicts := newSafetyFilter(cfg).checkDetails(d)
+	result := map[string]any{
+		"product": d,
+		"safe":    len(conflicts) == 0,
+	}
+	if len(conflicts) > 0 {
+		result["conflicts"] = conflicts
+	}
+	if cfg.Preferences.Healthy {
+		if w := d.healthWarnings(); len(w) > 0 {
+			result["health"] = w
+		}
+	}
+	printJSON(result)
+}
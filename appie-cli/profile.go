@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//go:embed profile.md.tmpl
+var profileTemplate string
+
+// profileSegmentHints select the member profile properties worth showing:
+// age range, life stage, food profile, diet, price segment, share of wallet
+// and favourite shopping day.
+var profileSegmentHints = []string{"age", "life", "food", "diet", "price", "wallet", "day", "household"}
+
+// tasteProfile is the data behind taste-profile.md, written as
+// taste-profile.json for programmatic use.
+type tasteProfile struct {
+	Generated        string            `json:"generated"`
+	MaxCookingTime   int               `json:"maxCookingTime"`
+	HouseholdSize    int               `json:"householdSize"`
+	Cuisines         cuisineProfile    `json:"cuisines"`
+	LikedRecipes     []string          `json:"likedRecipes"`
+	Dislikes         []string          `json:"dislikes"`
+	Allergies        []string          `json:"allergies"`
+	Segments         []profileProperty `json:"segments"`
+	Audiences        []string          `json:"audiences,omitempty"`
+	Staples          []string          `json:"staples"`
+	PreviouslyBought int               `json:"previouslyBought"`
+	Categories       []countedName     `json:"categories"`
+	Brands           []countedName     `json:"brands"`
+}
+
+type cuisineProfile struct {
+	Liked    []string `json:"liked"`
+	Disliked []string `json:"disliked"`
+}
+
+type countedName struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// topCounts returns the n most frequent names, ties broken alphabetically so
+// the output is stable between runs.
+func topCounts(counts map[string]int, n int) []countedName {
+	out := make([]countedName, 0, len(counts))
+	for name, c := range counts {
+		if name != "" {
+			out = append(out, countedName{Name: name, Count: c})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Name < out[j].Name
+	})
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// mergeUnique appends the names in add that are not yet in list,
+// case-insensitively.
+func mergeUnique(list []string, add ...string) []string {
+	seen := map[string]bool{}
+	for _, s := range list {
+		seen[strings.ToLower(s)] = true
+	}
+	for _, s := range add {
+		if s != "" && !seen[strings.ToLower(s)] {
+			seen[strings.ToLower(s)] = true
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// historyCuisines derives liked and disliked cuisines from meal-history.json:
+// a cuisine is liked when approved more often than rejected.
+func historyCuisines(h *mealHistory) (liked, disliked []string) {
+	score := map[string]int{}
+	for _, m := range h.Approved {
+		if c := recipeCuisine(m.Tags); c != "" {
+			score[c]++
+		}
+	}
+	for _, m := range h.Rejected {
+		if c := recipeCuisine(m.Tags); c != "" {
+			score[c]--
+		}
+	}
+	for _, c := range knownCuisines {
+		switch v, ok := score[c]; {
+		case ok && v > 0:
+			liked = append(liked, c)
+		case ok && v < 0:
+			disliked = append(disliked, c)
+		}
+	}
+	return liked, disliked
+}
+
+// selectSegments keeps the member profile properties matching keys, or the
+// profileSegmentHints when keys is empty.
+func selectSegments(props []profileProperty, keys []string) []profileProperty {
+	if len(keys) == 0 {
+		keys = profileSegmentHints
+	}
+	var out []profileProperty
+	for _, p := range props {
+		k := strings.ToLower(p.Key)
+		for _, hint := range keys {
+			if strings.Contains(k, strings.ToLower(hint)) {
+				out = append(out, p)
+				break
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+func (p *tasteProfile) markdown() (string, error) {
+	tmpl, err := template.New("profile").Funcs(template.FuncMap{
+		"join": func(s []string) string {
+			if len(s) == 0 {
+				return "TBD"
+			}
+			return strings.Join(s, ", ")
+		},
+	}).Parse(profileTemplate)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, p); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func runProfile(ctx context.Context, configPath string, argv []string) {
+	if len(argv) < 1 || argv[0] != "build" {
+		fmt.Fprintln(os.Stderr, "Usage: appie-cli profile build [--top <n>] [--keys a,b] [--no-member] [--force]")
+		os.Exit(1)
+	}
+	args := parseArgs(argv[1:], "member", "force")
+	top := args.num("top", 15)
+	if err := checkProfileFiles(args.flag("force", false)); err != nil {
+		fatal("%v", err)
+	}
+	client := mustAuth(ctx, configPath)
+	cfg := mustSkillConfig()
+	history, err := loadMealHistory()
+	if err != nil {
+		fatal("Load history failed: %v", err)
+	}
+	basics, err := loadWeeklyBasics()
+	if err != nil {
+		fatal("Load weekly basics failed: %v", err)
+	}
+
+	bought, err := getAllPreviouslyBought(ctx, client)
+	if err != nil {
+		fatal("Get previously bought failed: %v", err)
+	}
+	brands, categories := map[string]int{}, map[string]int{}
+	for _, p := range bought {
+		brands[p.Brand]++
+		categories[p.Category]++
+	}
+
+	profile := &tasteProfile{
+		Generated:        time.Now().Format(dateLayout),
+		MaxCookingTime:   cfg.MaxCookingTimeMinutes,
+		HouseholdSize:    cfg.HouseholdSize,
+		Dislikes:         mergeUnique(nil, cfg.Dislikes...),
+		Allergies:        mergeUnique(nil, cfg.Allergies...),
+		PreviouslyBought: len(bought),
+		Categories:       topCounts(categories, top),
+		Brands:           topCounts(brands, top),
+	}
+
+	liked, disliked := historyCuisines(history)
+	profile.Cuisines.Liked = mergeUnique(mergeUnique(nil, cfg.CuisinePreferences.Liked...), liked...)
+	profile.Cuisines.Disliked = mergeUnique(mergeUnique(nil, cfg.CuisinePreferences.Disliked...), disliked...)
+
+	for i := len(history.Approved) - 1; i >= 0 && len(profile.LikedRecipes) < top; i-- {
+		profile.LikedRecipes = mergeUnique(profile.LikedRecipes, history.Approved[i].RecipeName)
+	}
+	for _, r := range history.Rejected {
+		profile.Dislikes = mergeUnique(profile.Dislikes, fmt.Sprintf("%s (afgewezen: %s)", r.RecipeName, r.Reason))
+	}
+	for _, b := range append(basics.Weekly, basics.Biweekly...) {
+		if b.ID > 0 {
+			profile.Staples = mergeUnique(profile.Staples, b.Name)
+		}
+	}
+
+	if args.flag("member", true) {
+		member, err := getMemberProfile(ctx, client)
+		if err != nil {
+			fatal("Get member profile failed: %v", err)
+		}
+		profile.Segments = selectSegments(member.Properties, args.list("keys"))
+		profile.Audiences = member.Audiences
+	}
+
+	md, err := profile.markdown()
+	if err != nil {
+		fatal("Render profile failed: %v", err)
+	}
+	data, _ := json.MarshalIndent(profile, "", "  ")
+	files := map[string][]byte{
+		"taste-profile.md":   []byte(md),
+		"taste-profile.json": append(data, '\n'),
+	}
+	if err := writeProfileFiles(files); err != nil {
+		fatal("%v", err)
+	}
+	printJSON(profile)
+}
+
+// profileFiles are the files profile build writes.
+var profileFiles = []string{"taste-profile.md", "taste-profile.json"}
+
+// checkProfileFiles refuses to replace existing profile files without
+// force: taste-profile.md may have been edited by hand.
+func checkProfileFiles(force bool) error {
+	if force {
+		return nil
+	}
+	for _, name := range profileFiles {
+		if _, err := os.Stat(skillPath(name)); err == nil {
+			return fmt.Errorf("%s exists; pass --force to replace it (the old file is kept as %s.bak)", name, name)
+		}
+	}
+	return nil
+}
+
+// writeProfileFiles writes the profile files, keeping existing ones as
+// <name>.bak.
+func writeProfileFiles(files map[string][]byte) error {
+	for _, name := range profileFiles {
+		path := skillPath(name)
+		if err := os.Rename(path, path+".bak"); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("back up %s: %w", name, err)
+		}
+		if err := os.WriteFile(path, files[name], 0o644); err != nil {
+			return fmt.Errorf("write %s: %w", name, err)
+		}
+	}
+	return nil
+}
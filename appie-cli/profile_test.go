@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteProfileFiles(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("APPIE_SKILL_DIR", dir)
+	files := map[string][]byte{"taste-profile.md": []byte("new md\n"), "taste-profile.json": []byte("{}\n")}
+
+	if err := checkProfileFiles(false); err != nil {
+		t.Fatalf("no files yet: %v", err)
+	}
+	if err := writeProfileFiles(files); err != nil {
+		t.Fatal(err)
+	}
+
+	// A hand-edited profile is not replaced without force.
+	md := filepath.Join(dir, "taste-profile.md")
+	if err := os.WriteFile(md, []byte("edited by hand\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkProfileFiles(false); err == nil {
+		t.Fatal("existing profile replaced without --force")
+	}
+	if err := checkProfileFiles(true); err != nil {
+		t.Fatalf("with --force: %v", err)
+	}
+	if err := writeProfileFiles(files); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{md: "new md\n", md + ".bak": "edited by hand\n"} {
+		if got, err := os.ReadFile(path); err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", filepath.Base(path), got, err, want)
+		}
+	}
+}
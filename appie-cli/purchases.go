@@ -0,0 +1,552 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	appie "github.com/gwillem/appie-go"
+)
+
+// purchaseHistory mirrors purchase-history.json: a local archive of receipts
+// fetched from the API or imported from exports, so spending analysis keeps
+// working while the receipts endpoint is down, plus a log of the products the
+// CLI put on the list or order.
+type purchaseHistory struct {
+	Receipts      []storedReceipt `json:"receipts"`
+	ListAdditions []listAddition  `json:"listAdditions"`
+}
+
+// listAddition is a product added by batch-add, add-to-list, add-to-order or
+// reorder-meal, with its price and bonus status at that moment.
+type listAddition struct {
+	Date      string  `json:"date"`
+	ProductID int     `json:"productId"`
+	Title     string  `json:"title"`
+	Brand     string  `json:"brand,omitempty"`
+	Category  string  `json:"category,omitempty"`
+	Quantity  int     `json:"quantity"`
+	Price     float64 `json:"price"`
+	WasPrice  float64 `json:"wasPrice,omitempty"`
+	IsBonus   bool    `json:"isBonus,omitempty"`
+	Via       string  `json:"via"`
+}
+
+// receipt is appie.Receipt with items that keep the bonus mark a kassabon
+// prints after the amount.
+type receipt struct {
+	appie.Receipt
+	Items []receiptItem `json:"items,omitempty"`
+}
+
+type receiptItem struct {
+	appie.ReceiptItem
+	Bonus bool `json:"bonus,omitempty"`
+}
+
+// storedReceipt is a receipt plus where it came from ("api" or the imported
+// file name).
+type storedReceipt struct {
+	receipt
+	Source string `json:"source"`
+}
+
+// loadPurchaseHistory reads purchase-history.json; a missing file is an empty
+// archive.
+func loadPurchaseHistory() (*purchaseHistory, error) {
+	h := &purchaseHistory{}
+	data, err := os.ReadFile(skillPath("purchase-history.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, fmt.Errorf("parse purchase-history.json: %w", err)
+	}
+	return h, nil
+}
+
+// save writes purchase-history.json with the newest receipts first.
+func (h *purchaseHistory) save() error {
+	if h.Receipts == nil {
+		h.Receipts = []storedReceipt{}
+	}
+	if h.ListAdditions == nil {
+		h.ListAdditions = []listAddition{}
+	}
+	sort.SliceStable(h.Receipts, func(i, j int) bool { return h.Receipts[i].Date > h.Receipts[j].Date })
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(skillPath("purchase-history.json"), append(data, '\n'), 0o644)
+}
+
+// add stores r unless a receipt with the same transaction ID is already
+// archived. A stored summary without items is replaced by a detailed one.
+// It reports whether the archive changed.
+func (h *purchaseHistory) add(r storedReceipt) bool {
+	for i, old := range h.Receipts {
+		if old.TransactionID != r.TransactionID {
+			continue
+		}
+		if len(old.Items) == 0 && len(r.Items) > 0 {
+			h.Receipts[i] = r
+			return true
+		}
+		return false
+	}
+	h.Receipts = append(h.Receipts, r)
+	return true
+}
+
+// find returns the archived receipt with the given transaction ID.
+func (h *purchaseHistory) find(id string) *storedReceipt {
+	for i := range h.Receipts {
+		if h.Receipts[i].TransactionID == id {
+			return &h.Receipts[i]
+		}
+	}
+	return nil
+}
+
+var upstreamDown = regexp.MustCompile(`Name does not resolve|no such host`)
+
+// isUpstreamDown reports whether err means the receipts service is
+// unavailable (a 5xx or no connection) rather than that the request itself
+// was wrong.
+func isUpstreamDown(err error) bool {
+	var he *httpError
+	if errors.As(err, &he) {
+		return he.Status >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || upstreamDown.MatchString(err.Error())
+}
+
+// getReceipts fetches the receipt list via getREST rather than appie-go,
+// which drops the HTTP status when the error body is JSON.
+func getReceipts(ctx context.Context, client *appie.Client) ([]receipt, error) {
+	raw, err := getREST(ctx, client, "/mobile-services/v1/receipts")
+	if err != nil {
+		return nil, err
+	}
+	return parseReceiptJSON(bytes.TrimSpace(raw))
+}
+
+// getReceipt fetches one receipt with its items, like getReceipts.
+func getReceipt(ctx context.Context, client *appie.Client, id string) (*receipt, error) {
+	raw, err := getREST(ctx, client, "/mobile-services/v2/receipts/"+url.PathEscape(id))
+	if err != nil {
+		return nil, err
+	}
+	receipts, err := parseReceiptJSON(bytes.TrimSpace(raw))
+	if err != nil {
+		return nil, err
+	}
+	if len(receipts) == 0 {
+		return nil, fmt.Errorf("no receipt %s", id)
+	}
+	return &receipts[0], nil
+}
+
+// archiveReceipts adds receipts fetched from the API to the local archive.
+// Failing to write the archive is not worth failing the command for.
+func archiveReceipts(receipts ...receipt) {
+	h, err := loadPurchaseHistory()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		return
+	}
+	changed := false
+	for _, r := range receipts {
+		if r.TransactionID != "" && h.add(storedReceipt{receipt: r, Source: "api"}) {
+			changed = true
+		}
+	}
+	if changed {
+		if err := h.save(); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: save purchase-history.json: %v\n", err)
+		}
+	}
+}
+
+// pendingAdditions holds the list additions of this command until
+// flushAdditions writes them.
+var pendingAdditions []listAddition
+
+// recordAdditions logs products (ID to quantity) that via put on the list or
+// order. Products the command already fetched are passed as known, and so
+// are taken from the product index if seen today; only the rest is looked
+// up. Like archiveReceipts it only warns on failure: the products were
+// added, the log is a bonus.
+func recordAdditions(ctx context.Context, client *appie.Client, via string, qty map[int]int, known ...appie.Product) {
+	byID := map[int]appie.Product{}
+	for _, p := range known {
+		if qty[p.ID] > 0 && p.Title != "" {
+			byID[p.ID] = p
+		}
+	}
+	today := time.Now().Format(dateLayout)
+	var missing []int
+	for id := range qty {
+		if _, ok := byID[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		if idx, err := loadProductIndex(); err == nil {
+			for _, id := range missing {
+				if p, ok := idx.Products[id]; ok && p.Seen == today && p.Price.Now > 0 {
+					byID[id] = p.Product
+				}
+			}
+		}
+		missing = missing[:0]
+		for id := range qty {
+			if _, ok := byID[id]; !ok {
+				missing = append(missing, id)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		sort.Ints(missing)
+		products, err := client.GetProductsByIDs(ctx, missing)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: log list additions: %v\n", err)
+			return
+		}
+		for _, p := range products {
+			byID[p.ID] = p
+		}
+	}
+
+	ids := make([]int, 0, len(byID))
+	for id := range byID {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		p := byID[id]
+		pendingAdditions = append(pendingAdditions, listAddition{
+			Date:      today,
+			ProductID: p.ID,
+			Title:     p.Title,
+			Brand:     p.Brand,
+			Category:  p.Category,
+			Quantity:  qty[p.ID],
+			Price:     p.Price.Now,
+			WasPrice:  p.Price.Was,
+			IsBonus:   p.IsBonus,
+			Via:       via,
+		})
+	}
+}
+
+// flushAdditions writes the additions recorded by this command to
+// purchase-history.json in one go. main calls it once the command is done.
+func flushAdditions() {
+	if len(pendingAdditions) == 0 {
+		return
+	}
+	h, err := loadPurchaseHistory()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		return
+	}
+	h.ListAdditions = append(h.ListAdditions, pendingAdditions...)
+	pendingAdditions = nil
+	if err := h.save(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: save purchase-history.json: %v\n", err)
+	}
+}
+
+// listQuantities sums list item quantities per product, skipping free text.
+func listQuantities(items []appie.ListItem) map[int]int {
+	qty := map[int]int{}
+	for _, it := range items {
+		if it.ProductID > 0 {
+			qty[it.ProductID] += it.Quantity
+		}
+	}
+	return qty
+}
+
+// mustPurchaseHistory loads the archive for a fallback after upstreamErr.
+func mustPurchaseHistory(upstreamErr error) *purchaseHistory {
+	h, err := loadPurchaseHistory()
+	if err != nil {
+		fatal("%v (local archive: %v)", upstreamErr, err)
+	}
+	fmt.Fprintf(os.Stderr, "warning: receipts endpoint unavailable, using purchase-history.json: %v\n", upstreamErr)
+	return h
+}
+
+func runReceipts(ctx context.Context, configPath string, argv []string) {
+	if len(argv) > 0 && argv[0] == "import" {
+		runReceiptsImport(argv[1:])
+		return
+	}
+	args := parseArgs(argv, "redact", "local")
+	if args.flag("local", false) {
+		h, err := loadPurchaseHistory()
+		if err != nil {
+			fatal("Load purchase history failed: %v", err)
+		}
+		printRedacted(args, receiptFields, h.Receipts)
+		return
+	}
+	client := mustAuth(ctx, configPath)
+	receipts, err := getReceipts(ctx, client)
+	if err != nil {
+		if !isUpstreamDown(err) {
+			fatal("Get receipts failed: %v", err)
+		}
+		h := mustPurchaseHistory(err)
+		printRedacted(args, receiptFields, h.Receipts)
+		return
+	}
+	archiveReceipts(receipts...)
+	printRedacted(args, receiptFields, receipts)
+}
+
+func runReceipt(ctx context.Context, configPath string, argv []string) {
+	args := parseArgs(argv, "redact")
+	if len(args.pos) < 1 {
+		fmt.Fprintln(os.Stderr, "Usage: appie-cli receipt <transaction-id> [--no-redact]")
+		os.Exit(1)
+	}
+	client := mustAuth(ctx, configPath)
+	r, err := getReceipt(ctx, client, args.arg(0))
+	if err != nil {
+		if !isUpstreamDown(err) {
+			fatal("Get receipt failed: %v", err)
+		}
+		stored := mustPurchaseHistory(err).find(args.arg(0))
+		if stored == nil {
+			fatal("Get receipt failed: %v (not in purchase-history.json either)", err)
+		}
+		printRedacted(args, receiptFields, stored)
+		return
+	}
+	archiveReceipts(*r)
+	printRedacted(args, receiptFields, r)
+}
+
+func runReceiptsImport(files []string) {
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: appie-cli receipts import <file.json|file.txt>...")
+		os.Exit(1)
+	}
+	h, err := loadPurchaseHistory()
+	if err != nil {
+		fatal("Load purchase history failed: %v", err)
+	}
+	type fileResult struct {
+		File     string `json:"file"`
+		Found    int    `json:"found"`
+		Imported int    `json:"imported"`
+		Error    string `json:"error,omitempty"`
+	}
+	results := make([]fileResult, 0, len(files))
+	total := 0
+	for _, file := range files {
+		res := fileResult{File: file}
+		receipts, err := readReceiptFile(file)
+		if err != nil {
+			res.Error = err.Error()
+		}
+		res.Found = len(receipts)
+		for _, r := range receipts {
+			if h.add(storedReceipt{receipt: r, Source: filepath.Base(file)}) {
+				res.Imported++
+			}
+		}
+		total += res.Imported
+		results = append(results, res)
+	}
+	if total > 0 {
+		if err := h.save(); err != nil {
+			fatal("Save purchase history failed: %v", err)
+		}
+	}
+	printJSON(map[string]any{
+		"files":    results,
+		"imported": total,
+		"receipts": len(h.Receipts),
+	})
+}
+
+// readReceiptFile parses a JSON export or the text of a PDF receipt.
+func readReceiptFile(path string) ([]receipt, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		return parseReceiptJSON(trimmed)
+	}
+	r, err := parseReceiptText(string(data))
+	if err != nil {
+		return nil, err
+	}
+	return []receipt{*r}, nil
+}
+
+// importedReceipt accepts both the CLI's own receipt output and the raw API
+// shape (datetime, total, receiptItems).
+type importedReceipt struct {
+	receipt
+	DateTime     string        `json:"datetime"`
+	Total        float64       `json:"total"`
+	ReceiptItems []receiptItem `json:"receiptItems"`
+}
+
+func (r importedReceipt) normalize() receipt {
+	out := r.receipt
+	if out.Date == "" {
+		out.Date = r.DateTime
+	}
+	if out.TotalAmount == 0 {
+		out.TotalAmount = r.Total
+	}
+	if len(out.Items) == 0 {
+		out.Items = r.ReceiptItems
+	}
+	if out.TransactionID == "" {
+		out.TransactionID = syntheticTransactionID(out)
+	}
+	return out
+}
+
+// parseReceiptJSON reads a receipt, an array of receipts or {"receipts": [...]}.
+func parseReceiptJSON(data []byte) ([]receipt, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty receipt data")
+	}
+	var list []importedReceipt
+	if data[0] == '{' {
+		var wrapped struct {
+			Receipts []importedReceipt `json:"receipts"`
+		}
+		if err := json.Unmarshal(data, &wrapped); err == nil && wrapped.Receipts != nil {
+			list = wrapped.Receipts
+		} else {
+			var single importedReceipt
+			if err := json.Unmarshal(data, &single); err != nil {
+				return nil, err
+			}
+			list = []importedReceipt{single}
+		}
+	} else if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	out := make([]receipt, 0, len(list))
+	for _, r := range list {
+		out = append(out, r.normalize())
+	}
+	return out, nil
+}
+
+var (
+	receiptLine  = regexp.MustCompile(`^\s*(?:(\d+)\s+)?(.+?)\s+(-?\d+[.,]\d{2})(?:\s+(-?\d+[.,]\d{2}))?(\s+B)?\s*$`)
+	receiptTotal = regexp.MustCompile(`(?i)^\s*totaal\s+(-?\d+[.,]\d{2})`)
+	receiptDate  = regexp.MustCompile(`(\d{2})[-/](\d{2})[-/](\d{4})(?:\s+(\d{2}:\d{2}))?`)
+	receiptStore = regexp.MustCompile(`(?i)^\s*(albert heijn|ah)\b.*$`)
+)
+
+// receiptSkip marks receipt lines that look like items but are not.
+var receiptSkip = []string{"subtotaal", "totaal", "bonuskaart", "koopzegels", "betaald", "pinnen", "wisselgeld", "btw", "statiegeld retour", "uw voordeel"}
+
+// parseReceiptText reads the text of an AH kassabon (e.g. pdftotext output):
+// "<qty> <description> [unit price] <amount> [B]" lines, B marking a bonus
+// product, the BONUS discount lines under them (no quantity, negative
+// amount), a TOTAAL line, a date and the store on the first "Albert Heijn
+// ..." line.
+func parseReceiptText(text string) (*receipt, error) {
+	r := &receipt{}
+	for _, line := range strings.Split(text, "\n") {
+		if r.Date == "" {
+			if m := receiptDate.FindStringSubmatch(line); m != nil {
+				r.Date = m[3] + "-" + m[2] + "-" + m[1]
+				if m[4] != "" {
+					r.Date += "T" + m[4] + ":00"
+				}
+			}
+		}
+		if r.StoreName == "" && receiptStore.MatchString(line) {
+			r.StoreName = strings.Join(strings.Fields(line), " ")
+		}
+		if m := receiptTotal.FindStringSubmatch(line); m != nil {
+			r.TotalAmount = parseEuro(m[1])
+			continue
+		}
+		m := receiptLine.FindStringSubmatch(line)
+		if m == nil || hasAnyPrefix(strings.ToLower(m[2]), receiptSkip) {
+			continue
+		}
+		if m[1] == "" && !strings.HasPrefix(strings.ToLower(m[2]), "bonus") {
+			continue // address, opening hours and payment lines
+		}
+		qty, _ := strconv.Atoi(m[1])
+		item := receiptItem{ReceiptItem: appie.ReceiptItem{Description: m[2], Quantity: qty, Amount: parseEuro(m[3])}, Bonus: m[5] != ""}
+		if m[4] != "" {
+			item.UnitPrice, item.Amount = item.Amount, parseEuro(m[4])
+		} else if qty > 0 {
+			item.UnitPrice = roundCents(item.Amount / float64(qty))
+		}
+		r.Items = append(r.Items, item)
+	}
+	if len(r.Items) == 0 {
+		return nil, errors.New("no receipt lines found")
+	}
+	if r.TotalAmount == 0 {
+		for _, it := range r.Items {
+			r.TotalAmount += it.Amount
+		}
+		r.TotalAmount = roundCents(r.TotalAmount)
+	}
+	r.TransactionID = syntheticTransactionID(*r)
+	return r, nil
+}
+
+func parseEuro(s string) float64 {
+	f, _ := strconv.ParseFloat(strings.ReplaceAll(s, ",", "."), 64)
+	return f
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// syntheticTransactionID derives a stable ID for imported receipts that have
+// none, so importing the same file twice does not duplicate it.
+func syntheticTransactionID(r receipt) string {
+	sum := sha1.New()
+	fmt.Fprintf(sum, "%s|%s|%.2f", r.Date, r.StoreName, r.TotalAmount)
+	for _, it := range r.Items {
+		fmt.Fprintf(sum, "|%s|%d|%.2f", it.Description, it.Quantity, it.Amount)
+	}
+	return "import-" + hex.EncodeToString(sum.Sum(nil))[:12]
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	appie "github.com/gwillem/appie-go"
+)
+
+func TestRecordAdditions(t *testing.T) {
+	t.Setenv("APPIE_SKILL_DIR", t.TempDir())
+	idx := &productIndex{Products: map[int]indexedProduct{}}
+	idx.add("search", appie.Product{ID: 2, Title: "AH Halfvolle melk", Price: appie.Price{Now: 1.29}})
+	if err := idx.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Product 1 comes from the command, product 2 from today's index: no
+	// lookup, so no client is needed.
+	known := []appie.Product{
+		{ID: 1, Title: "AH Basmati rijst", Price: appie.Price{Now: 2.49}, IsBonus: true},
+		{ID: 9, Title: "not added"},
+	}
+	recordAdditions(context.Background(), nil, "batch-add", map[int]int{1: 2, 2: 1}, known...)
+	recordAdditions(context.Background(), nil, "add-to-list", map[int]int{2: 3})
+	if _, err := os.Stat(skillPath("purchase-history.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("purchase-history.json written before flush: %v", err)
+	}
+	flushAdditions()
+
+	h, err := loadPurchaseHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := time.Now().Format(dateLayout)
+	want := []listAddition{
+		{Date: today, ProductID: 1, Title: "AH Basmati rijst", Quantity: 2, Price: 2.49, IsBonus: true, Via: "batch-add"},
+		{Date: today, ProductID: 2, Title: "AH Halfvolle melk", Quantity: 1, Price: 1.29, Via: "batch-add"},
+		{Date: today, ProductID: 2, Title: "AH Halfvolle melk", Quantity: 3, Price: 1.29, Via: "add-to-list"},
+	}
+	if len(h.ListAdditions) != len(want) {
+		t.Fatalf("listAdditions = %+v, want %d", h.ListAdditions, len(want))
+	}
+	for i, w := range want {
+		if h.ListAdditions[i] != w {
+			t.Errorf("listAdditions[%d] = %+v, want %+v", i, h.ListAdditions[i], w)
+		}
+	}
+	if len(pendingAdditions) != 0 {
+		t.Errorf("pendingAdditions not cleared: %+v", pendingAdditions)
+	}
+}
+
+// kassabon is pdftotext -layout output of an AH receipt, shortened.
+const kassabon = `
+                      Albert Heijn 1421
+                   Stationsplein 12 Utrecht
+                      030-1234567
+
+  AANTAL OMSCHRIJVING                  PRIJS   BEDRAG
+         BONUSKAART                          xx1234
+       1 AH HALFVOLLE MELK                       1,29
+       2 AH BANANEN                     0,99     1,98
+       1 LAYS CHIPS PAPRIKA                      2,19 B
+         BONUS LAYS CHIPS                       -0,75
+       3 AH BASMATI RIJST               2,49     7,47 B
+         BONUS 2E HALVE PRIJS                   -1,24
+         SUBTOTAAL                              10,94
+         UW VOORDEEL                             1,99
+         TOTAAL                                 10,94
+         PINNEN                                 10,94
+
+  BTW   OVER    EUR
+  9%   10,04   0,90
+
+  18-03-2026 14:32   1421   3   107
+`
+
+func TestParseReceiptText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		date  string
+		store string
+		total float64
+		items []receiptItem
+	}{
+		{
+			name:  "kassabon with bonus lines",
+			text:  kassabon,
+			date:  "2026-03-18T14:32:00",
+			store: "Albert Heijn 1421",
+			total: 10.94,
+			items: []receiptItem{
+				{ReceiptItem: appie.ReceiptItem{Description: "AH HALFVOLLE MELK", Quantity: 1, Amount: 1.29, UnitPrice: 1.29}},
+				{ReceiptItem: appie.ReceiptItem{Description: "AH BANANEN", Quantity: 2, Amount: 1.98, UnitPrice: 0.99}},
+				{ReceiptItem: appie.ReceiptItem{Description: "LAYS CHIPS PAPRIKA", Quantity: 1, Amount: 2.19, UnitPrice: 2.19}, Bonus: true},
+				{ReceiptItem: appie.ReceiptItem{Description: "BONUS LAYS CHIPS", Amount: -0.75}},
+				{ReceiptItem: appie.ReceiptItem{Description: "AH BASMATI RIJST", Quantity: 3, Amount: 7.47, UnitPrice: 2.49}, Bonus: true},
+				{ReceiptItem: appie.ReceiptItem{Description: "BONUS 2E HALVE PRIJS", Amount: -1.24}},
+			},
+		},
+		{
+			name:  "no TOTAAL line",
+			text:  "AH to go Amsterdam\n02/03/2026\n1 AH Croissant 0,89\n2 Spa Blauw 1,10 2,20\n",
+			date:  "2026-03-02",
+			store: "AH to go Amsterdam",
+			total: 3.09,
+			items: []receiptItem{
+				{ReceiptItem: appie.ReceiptItem{Description: "AH Croissant", Quantity: 1, Amount: 0.89, UnitPrice: 0.89}},
+				{ReceiptItem: appie.ReceiptItem{Description: "Spa Blauw", Quantity: 2, Amount: 2.20, UnitPrice: 1.10}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		r, err := parseReceiptText(tt.text)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if r.Date != tt.date || r.StoreName != tt.store || r.TotalAmount != tt.total {
+			t.Errorf("%s: date %q, store %q, total %.2f; want %q, %q, %.2f",
+				tt.name, r.Date, r.StoreName, r.TotalAmount, tt.date, tt.store, tt.total)
+		}
+		if len(r.Items) != len(tt.items) {
+			t.Errorf("%s: items = %+v, want %d", tt.name, r.Items, len(tt.items))
+			continue
+		}
+		var sum float64
+		for i, want := range tt.items {
+			if r.Items[i] != want {
+				t.Errorf("%s: items[%d] = %+v, want %+v", tt.name, i, r.Items[i], want)
+			}
+			sum += r.Items[i].Amount
+		}
+		if roundCents(sum) != tt.total {
+			t.Errorf("%s: items add up to %.2f, want %.2f", tt.name, sum, tt.total)
+		}
+	}
+
+	if _, err := parseReceiptText("Albert Heijn\nTOTAAL 0,00\n"); err == nil {
+		t.Error("receipt without items parsed")
+	}
+}
+
+func TestGetReceiptsUpstreamDown(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		down   bool
+	}{
+		{503, `{"message":"appie-receipt-bff.ctp-checkout-and-receipts-prd: Name does not resolve"}`, true},
+		{500, `internal error`, true},
+		{401, `{"message":"unauthorized"}`, false},
+		{404, `{"code":"NOT_FOUND","message":"no receipts"}`, false},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, tt.body, tt.status)
+		}))
+		t.Setenv("APPIE_REST_URL", srv.URL)
+		_, err := getReceipts(context.Background(), appie.New(appie.WithTokens("token", "")))
+		srv.Close()
+		if err == nil {
+			t.Errorf("%d: no error", tt.status)
+			continue
+		}
+		if got := isUpstreamDown(err); got != tt.down {
+			t.Errorf("%d: isUpstreamDown(%v) = %v, want %v", tt.status, err, got, tt.down)
+		}
+	}
+}
+
+func TestGetReceipts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"receipts":[{"transactionId":"t1","datetime":"2026-03-18T14:32:00","storeName":"AH Utrecht","total":10.94}]}`))
+	}))
+	defer srv.Close()
+	t.Setenv("APPIE_REST_URL", srv.URL)
+	receipts, err := getReceipts(context.Background(), appie.New(appie.WithTokens("token", "")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := appie.Receipt{TransactionID: "t1", Date: "2026-03-18T14:32:00", StoreName: "AH Utrecht", TotalAmount: 10.94}
+	if len(receipts) != 1 || receipts[0].TransactionID != want.TransactionID || receipts[0].Date != want.Date || receipts[0].TotalAmount != want.TotalAmount {
+		t.Errorf("getReceipts = %+v, want [%+v]", receipts, want)
+	}
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	appie "github.com/gwillem/appie-go"
+)
+
+// recipeSummary is a single hit from searchRecipes.
+type recipeSummary struct {
+	ID       int             `json:"id"`
+	Title    string          `json:"title"`
+	Slug     string          `json:"slug"`
+	CookTime int             `json:"cookTime"`
+	Images   json.RawMessage `json:"images,omitempty"`
+}
+
+// recipeSearchResult is the typed form of searchRecipes' response.
+type recipeSearchResult struct {
+	Result []recipeSummary `json:"result"`
+	Page   json.RawMessage `json:"page,omitempty"`
+}
+
+// recipeDetails is the typed form of getRecipe's response.
+type recipeDetails struct {
+	ID          int                `json:"id"`
+	Title       string             `json:"title"`
+	Slug        string             `json:"slug"`
+	Description string             `json:"description"`
+	CookTime    int                `json:"cookTime"`
+	PrepTime    int                `json:"prepTime"`
+	Servings    int                `json:"servings"`
+	Tags        []string           `json:"tags"`
+	Ingredients []recipeIngredient `json:"ingredients"`
+	Nutritions  []recipeNutrition  `json:"nutritions"`
+}
+
+type recipeIngredient struct {
+	Text     string     `json:"text"`
+	Quantity float64    `json:"quantity"`
+	Name     recipeNoun `json:"name"`
+	Unit     recipeNoun `json:"unit"`
+}
+
+type recipeNoun struct {
+	Singular string `json:"singular"`
+	Plural   string `json:"plural"`
+}
+
+type recipeNutrition struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+// name returns the ingredient name without quantity, falling back to the text.
+func (i recipeIngredient) name() string {
+	if i.Name.Singular != "" {
+		return i.Name.Singular
+	}
+	if i.Name.Plural != "" {
+		return i.Name.Plural
+	}
+	return i.Text
+}
+
+// words returns every text field of the ingredient, for matching.
+func (i recipeIngredient) words() string {
+	return strings.Join([]string{i.Text, i.Name.Singular, i.Name.Plural}, " ")
+}
+
+// searchRecipesTyped runs searchRecipes and decodes the result.
+func searchRecipesTyped(ctx context.Context, client *appie.Client, query string, size int) (*recipeSearchResult, error) {
+	raw, err := searchRecipes(ctx, client, query, size)
+	if err != nil {
+		return nil, err
+	}
+	var result recipeSearchResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("parse error: %w", err)
+	}
+	return &result, nil
+}
+
+// recipeSearchFilter is a facet filter in recipeSearch's query input.
+type recipeSearchFilter struct {
+	Group  string   `json:"group"`
+	Values []string `json:"values"`
+}
+
+// searchRecipesFiltered searches with the filters recipeSearch supports
+// sent along. With overFetch it searches for more than size hits when
+// filters remain for the client, so enough are left after filtering. The
+// facet input is undocumented: when the API rejects it, the search runs
+// without and every filter stays client side. The returned filters record
+// which happened.
+func searchRecipesFiltered(ctx context.Context, client *appie.Client, query string, size int, filters recipeFilters, overFetch bool) (*recipeSearchResult, recipeFilters, error) {
+	fetch := func() int {
+		if overFetch {
+			return filters.fetchSize(size)
+		}
+		return size
+	}
+	if facets := filters.searchFacets(); len(facets) > 0 {
+		filters.faceted = true
+		var data struct {
+			RecipeSearch recipeSearchResult `json:"recipeSearch"`
+		}
+		vars := map[string]any{"query": query, "size": fetch(), "filters": facets}
+		err := runCatalogQuery(ctx, client, "recipe-search-filtered", vars, &data)
+		var gqlErr graphqlErrors
+		if err == nil {
+			return &data.RecipeSearch, filters, nil
+		}
+		if !errors.As(err, &gqlErr) {
+			return nil, filters, err
+		}
+		fmt.Fprintf(os.Stderr, "warning: recipe search rejected the filters (%v); filtering client side\n", err)
+		filters.faceted = false
+	}
+	result, err := searchRecipesTyped(ctx, client, query, fetch())
+	return result, filters, err
+}
+
+// getRecipeDetails runs getRecipe and decodes the result.
+func getRecipeDetails(ctx context.Context, client *appie.Client, id int) (*recipeDetails, error) {
+	raw, err := getRecipe(ctx, client, id)
+	if err != nil {
+		return nil, err
+	}
+	var details *recipeDetails
+	if err := json.Unmarshal(raw, &details); err != nil {
+		return nil, fmt.Errorf("parse error: %w", err)
+	}
+	if details == nil {
+		return nil, fmt.Errorf("recipe %d not found", id)
+	}
+	return details, nil
+}
+
+// getRecipesDetails fetches details for several recipes concurrently. The
+// result is indexed like ids; failed lookups are nil with their error in errs.
+func getRecipesDetails(ctx context.Context, client *appie.Client, ids []int) ([]*recipeDetails, []error) {
+	details := make([]*recipeDetails, len(ids))
+	errs := make([]error, len(ids))
+	parallel(len(ids), 4, func(i int) {
+		details[i], errs[i] = getRecipeDetails(ctx, client, ids[i])
+	})
+	return details, errs
+}
+
+// parallel calls fn for 0..n-1 using at most workers goroutines.
+func parallel(n, workers int, fn func(i int)) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, workers)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// recipeFilters narrows recipe search results. Zero values disable a filter.
+// Course and cuisine are recipeSearch facets (see searchRecipesFiltered);
+// the rest is applied client side on top of the query text.
+type recipeFilters struct {
+	MaxCookTime int
+	MinServings int
+	Tags        []string
+	ExcludeTags []string
+	Cuisine     string
+	Course      string
+	Diet        string
+	Sort        string
+	Seasonal    bool
+	Month       time.Month
+
+	// faceted is set when recipeSearch applied the facets itself.
+	faceted bool
+}
+
+// searchFacets are the filters recipeSearch takes as facet groups, named
+// like the filters of Allerhande's recipe search.
+func (f recipeFilters) searchFacets() []recipeSearchFilter {
+	var facets []recipeSearchFilter
+	if f.Course != "" {
+		facets = append(facets, recipeSearchFilter{Group: "menugang", Values: []string{strings.ToLower(f.Course)}})
+	}
+	if f.Cuisine != "" {
+		facets = append(facets, recipeSearchFilter{Group: "keuken", Values: []string{strings.ToLower(f.Cuisine)}})
+	}
+	return facets
+}
+
+// fetchSize is how many hits to search for to end up with size: three
+// times as many when filters drop hits client side.
+func (f recipeFilters) fetchSize(size int) int {
+	if f.MaxCookTime > 0 || f.needsDetails() {
+		return min(size*3, 100)
+	}
+	return size
+}
+
+// dietTags maps --diet values to the Allerhande tags that satisfy them.
+var dietTags = map[string][]string{
+	"vegetarian":   {"vegetarisch", "veganistisch", "vegan"},
+	"vegetarisch":  {"vegetarisch", "veganistisch", "vegan"},
+	"vegan":        {"veganistisch", "vegan"},
+	"veganistisch": {"veganistisch", "vegan"},
+}
+
+// recipeFilterFlags are the flags recipeFiltersFromArgs reads.
+var recipeFilterFlags = []string{"max-time", "min-servings", "tags", "exclude-tags", "cuisine", "course", "diet", "sort", "seasonal", "month"}
+
+// recipeFiltersFromArgs reads the search-recipes flags. --max-time defaults
+// to max_cooking_time_minutes from config.json; --max-time 0 disables it.
+// --seasonal ranks by seasonal share for --month, by default this month.
+func recipeFiltersFromArgs(args cmdArgs, cfg *skillConfig) recipeFilters {
+	month := args.num("month", int(time.Now().Month()))
+	if month < 1 || month > 12 {
+		fatal("Invalid --month %d (use 1-12)", month)
+	}
+	return recipeFilters{
+		MaxCookTime: args.num("max-time", cfg.MaxCookingTimeMinutes),
+		MinServings: args.num("min-servings", 0),
+		Tags:        args.list("tags"),
+		ExcludeTags: args.list("exclude-tags"),
+		Cuisine:     args.str("cuisine", ""),
+		Course:      args.str("course", ""),
+		Diet:        strings.ToLower(args.str("diet", "")),
+		Sort:        args.str("sort", "relevance"),
+		Seasonal:    args.flag("seasonal", false),
+		Month:       time.Month(month),
+	}
+}
+
+func (f recipeFilters) empty() bool {
+	return f.MaxCookTime <= 0 && !f.needsDetails() && f.Sort == "relevance"
+}
+
+// needsDetails reports whether filtering needs getRecipe, which is the only
+// source of servings, tags and ingredients. Facets recipeSearch applied
+// need no check.
+func (f recipeFilters) needsDetails() bool {
+	return f.Seasonal || f.MinServings > 0 || len(f.Tags) > 0 || len(f.ExcludeTags) > 0 ||
+		!f.faceted && (f.Cuisine != "" || f.Course != "") || f.Diet != ""
+}
+
+// matchSummary applies the filters that a search hit can answer.
+func (f recipeFilters) matchSummary(s recipeSummary) bool {
+	return f.MaxCookTime <= 0 || s.CookTime <= f.MaxCookTime
+}
+
+// matchDetails applies the filters that need the full recipe.
+func (f recipeFilters) matchDetails(d *recipeDetails) bool {
+	if f.MinServings > 0 && d.Servings < f.MinServings {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !hasTag(d.Tags, tag) {
+			return false
+		}
+	}
+	for _, tag := range f.ExcludeTags {
+		if hasTag(d.Tags, tag) {
+			return false
+		}
+	}
+	if !f.faceted && f.Cuisine != "" && !hasTag(d.Tags, f.Cuisine) {
+		return false
+	}
+	if !f.faceted && f.Course != "" && !hasTag(d.Tags, f.Course) {
+		return false
+	}
+	if f.Diet != "" {
+		want, ok := dietTags[f.Diet]
+		if !ok {
+			want = []string{f.Diet}
+		}
+		if !hasAnyTag(d.Tags, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasTag matches tags case-insensitively, allowing "italiaans" to match
+// "Italiaanse keuken".
+func hasTag(tags []string, want string) bool {
+	want = strings.ToLower(want)
+	for _, t := range tags {
+		if strings.Contains(strings.ToLower(t), want) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyTag(tags, want []string) bool {
+	for _, w := range want {
+		if hasTag(tags, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// recipeMatch is a search hit that passed selectRecipes. It marshals as the
+// plain search hit, plus its seasonality with --seasonal; details is set
+// when the filters needed them.
+type recipeMatch struct {
+	recipeSummary
+	Seasonal *seasonality `json:"seasonal,omitempty"`
+	details  *recipeDetails
+}
+
+// selectRecipes applies search filters and the safety filter to search hits.
+// Recipe details are fetched when withDetails is set or when one of the
+// filters needs ingredients or tags. Hits are returned in relevance order
+// unless filters.Sort is "time" or filters.Seasonal is set. A limit above
+// zero stops once that many hits passed, so details are only fetched for as
+// many hits as needed; sorting by time or season looks at every hit.
+func selectRecipes(ctx context.Context, client *appie.Client, hits []recipeSummary, filters recipeFilters, safety *safetyFilter, withDetails bool, limit int) ([]recipeMatch, []exclusion) {
+	candidates := make([]recipeMatch, 0, len(hits))
+	for _, h := range hits {
+		if filters.matchSummary(h) {
+			candidates = append(candidates, recipeMatch{recipeSummary: h})
+		}
+	}
+	if limit <= 0 {
+		limit = len(candidates)
+	}
+	need := limit
+	if filters.Seasonal || filters.Sort == "time" {
+		need = len(candidates)
+	}
+
+	var excluded []exclusion
+	if withDetails || filters.needsDetails() || !safety.empty() {
+		var kept []recipeMatch
+		for start := 0; start < len(candidates) && len(kept) < need; start += limit {
+			batch := candidates[start:min(start+limit, len(candidates))]
+			ids := make([]int, len(batch))
+			for i, c := range batch {
+				ids[i] = c.ID
+			}
+			details, errs := getRecipesDetails(ctx, client, ids)
+			for i, c := range batch {
+				if errs[i] != nil {
+					if !safety.empty() {
+						// Without ingredients we cannot vouch for the recipe.
+						excluded = append(excluded, exclusion{ID: c.ID, Title: c.Title, Conflicts: []conflict{{Kind: "unknown", Match: errs[i].Error()}}})
+					}
+					continue
+				}
+				if !filters.matchDetails(details[i]) {
+					continue
+				}
+				if conflicts := safety.checkRecipe(details[i]); len(conflicts) > 0 {
+					excluded = append(excluded, exclusion{ID: c.ID, Title: c.Title, Conflicts: conflicts})
+					continue
+				}
+				c.details = details[i]
+				if filters.Seasonal {
+					c.Seasonal = recipeSeasonality(details[i], filters.Month)
+				}
+				kept = append(kept, c)
+			}
+		}
+		candidates = kept
+	}
+
+	if filters.Seasonal && filters.Sort != "time" {
+		sortBySeason(candidates)
+	}
+
+	if filters.Sort == "time" {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			a, b := candidates[i].CookTime, candidates[j].CookTime
+			if a == 0 || b == 0 {
+				return b == 0 && a != 0
+			}
+			return a < b
+		})
+	}
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates, excluded
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	appie "github.com/gwillem/appie-go"
+)
+
+// recipeServer fakes the GraphQL API: searches return hits 1..size, every
+// other recipe is vegetarian and recipe 3 takes 90 minutes. It counts the
+// recipe lookups, and with rejectFacets fails the faceted search like a
+// schema change would.
+type recipeServer struct {
+	rejectFacets bool
+	searches     []map[string]any
+	lookups      atomic.Int32
+}
+
+func (rs *recipeServer) start(t *testing.T) *appie.Client {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		switch {
+		case strings.Contains(req.Query, "recipeSearch"):
+			if rs.rejectFacets && req.Variables["filters"] != nil {
+				w.Write([]byte(`{"errors": [{"message": "Unknown argument \"filters\" on field \"recipeSearch\""}]}`))
+				return
+			}
+			rs.searches = append(rs.searches, req.Variables)
+			var hits []string
+			for id := 1; id <= int(req.Variables["size"].(float64)); id++ {
+				hits = append(hits, fmt.Sprintf(`{"id": %d, "title": "Recept %d", "cookTime": %d}`, id, id, cookTime(id)))
+			}
+			fmt.Fprintf(w, `{"data": {"recipeSearch": {"result": [%s], "page": {}}}}`, strings.Join(hits, ","))
+		default:
+			rs.lookups.Add(1)
+			id := int(req.Variables["id"].(float64))
+			tags := `["Italiaans"]`
+			if id%2 == 0 {
+				tags = `["Italiaans", "Vegetarisch"]`
+			}
+			fmt.Fprintf(w, `{"data": {"recipe": {"id": %d, "title": "Recept %d", "cookTime": %d, "servings": 4, "tags": %s,
+				"ingredients": [{"text": "200 g spaghetti", "name": {"singular": "spaghetti"}}]}}}`, id, id, cookTime(id), tags)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("APPIE_GRAPHQL_URL", srv.URL)
+	return appie.New(appie.WithTokens("token", ""))
+}
+
+func cookTime(id int) int {
+	if id == 3 {
+		return 90
+	}
+	return 20
+}
+
+func TestSearchRecipesFiltered(t *testing.T) {
+	tests := []struct {
+		name         string
+		filters      recipeFilters
+		rejectFacets bool
+		overFetch    bool
+		wantSize     float64
+		wantFacets   bool
+		faceted      bool
+	}{
+		{"no filters", recipeFilters{Sort: "relevance"}, false, true, 10, false, false},
+		{"max time over-fetches", recipeFilters{MaxCookTime: 45}, false, true, 30, false, false},
+		{"facets need no over-fetch", recipeFilters{Course: "Hoofdgerecht", Cuisine: "Italiaans"}, false, true, 10, true, true},
+		{"rejected facets stay client side", recipeFilters{Course: "hoofdgerecht"}, true, true, 30, false, false},
+		{"suggest does not over-fetch", recipeFilters{MaxCookTime: 45, Diet: "vegan"}, false, false, 10, false, false},
+	}
+	for _, tt := range tests {
+		rs := &recipeServer{rejectFacets: tt.rejectFacets}
+		client := rs.start(t)
+		_, filters, err := searchRecipesFiltered(context.Background(), client, "pasta", 10, tt.filters, tt.overFetch)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(rs.searches) != 1 {
+			t.Errorf("%s: %d searches, want 1", tt.name, len(rs.searches))
+			continue
+		}
+		vars := rs.searches[0]
+		if vars["size"] != tt.wantSize || (vars["filters"] != nil) != tt.wantFacets || filters.faceted != tt.faceted {
+			t.Errorf("%s: size %v, filters %v, faceted %v; want %v, %v, %v", tt.name, vars["size"], vars["filters"], filters.faceted, tt.wantSize, tt.wantFacets, tt.faceted)
+		}
+	}
+}
+
+func TestSearchFacets(t *testing.T) {
+	f := recipeFilters{Course: "Hoofdgerecht", Cuisine: "Italiaans", Diet: "vegan", Tags: []string{"snel"}}
+	want := `[{"group":"menugang","values":["hoofdgerecht"]},{"group":"keuken","values":["italiaans"]}]`
+	if got, _ := json.Marshal(f.searchFacets()); string(got) != want {
+		t.Errorf("searchFacets = %s, want %s", got, want)
+	}
+	if !f.needsDetails() {
+		t.Error("diet and tags need details")
+	}
+	f = recipeFilters{Course: "Hoofdgerecht", faceted: true}
+	if f.needsDetails() {
+		t.Error("faceted course needs details")
+	}
+}
+
+func TestSelectRecipes(t *testing.T) {
+	hits := make([]recipeSummary, 30)
+	for i := range hits {
+		hits[i] = recipeSummary{ID: i + 1, Title: fmt.Sprintf("Recept %d", i+1), CookTime: cookTime(i + 1)}
+	}
+	safety := newSafetyFilter(&skillConfig{Dislikes: []string{"koriander"}})
+	tests := []struct {
+		name        string
+		filters     recipeFilters
+		limit       int
+		wantIDs     []int
+		wantLookups int32
+	}{
+		// Safety needs details, but only for the first batch.
+		{"limit", recipeFilters{MaxCookTime: 45, Sort: "relevance"}, 5, []int{1, 2, 4, 5, 6}, 5},
+		// Half the recipes are vegetarian: two batches of five.
+		{"diet", recipeFilters{Diet: "vegetarisch", Sort: "relevance"}, 5, []int{2, 4, 6, 8, 10}, 10},
+		// Sorting by time looks at every hit.
+		{"sort by time", recipeFilters{MaxCookTime: 45, Sort: "time"}, 5, []int{1, 2, 4, 5, 6}, 29},
+		{"no limit", recipeFilters{Diet: "vegetarisch", Sort: "relevance"}, 0, nil, 30},
+	}
+	for _, tt := range tests {
+		rs := &recipeServer{}
+		client := rs.start(t)
+		kept, _ := selectRecipes(context.Background(), client, hits, tt.filters, safety, false, tt.limit)
+		var ids []int
+		for _, k := range kept {
+			ids = append(ids, k.ID)
+		}
+		if tt.wantIDs != nil && fmt.Sprint(ids) != fmt.Sprint(tt.wantIDs) {
+			t.Errorf("%s: ids %v, want %v", tt.name, ids, tt.wantIDs)
+		}
+		if tt.limit == 0 && len(ids) != 15 {
+			t.Errorf("%s: %d recipes, want 15", tt.name, len(ids))
+		}
+		if got := rs.lookups.Load(); got != tt.wantLookups {
+			t.Errorf("%s: %d recipe lookups, want %d", tt.name, got, tt.wantLookups)
+		}
+	}
+}
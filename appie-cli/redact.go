@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+)
+
+const redacted = "[redacted]"
+
+// redactor keeps the JSON fields it allows and blanks out everything else, so
+// a field added upstream (a phone number, an address) stays hidden until
+// someone decides it is safe to show.
+type redactor map[string]bool
+
+func newRedactor(fields ...string) redactor {
+	r := redactor{}
+	for _, f := range fields {
+		r[f] = true
+	}
+	return r
+}
+
+// memberFields are the member fields that describe AH's segmentation rather
+// than the person: no name, email, address, birth date or card numbers.
+var memberFields = newRedactor(
+	"audiences", "customerProfileAudiences", "customerProfileProperties", "key", "value",
+)
+
+// receiptFields cover what was bought, where and for how much.
+var receiptFields = newRedactor(
+	"transactionId", "date", "storeName", "storeId", "totalAmount",
+	"items", "description", "quantity", "amount", "unitPrice", "productId", "source",
+)
+
+// apply returns v as generic JSON with every non-allowed object field
+// replaced by "[redacted]". Arrays and allowed objects are walked recursively.
+func (r redactor) apply(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return r.walk(doc), nil
+}
+
+func (r redactor) walk(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, field := range v {
+			if r[k] {
+				v[k] = r.walk(field)
+			} else if field != nil && field != "" {
+				v[k] = redacted
+			}
+		}
+		return v
+	case []any:
+		for i := range v {
+			v[i] = r.walk(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// stdoutIsTerminal reports whether output goes to a terminal rather than a
+// pipe or file, where it might end up in logs or an agent transcript.
+func stdoutIsTerminal() bool {
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// printRedacted prints v through r unless --no-redact is given. Redaction is
+// on by default when stdout is not a terminal.
+func printRedacted(args cmdArgs, r redactor, v any) {
+	if !args.flag("redact", !stdoutIsTerminal()) {
+		printJSON(v)
+		return
+	}
+	out, err := r.apply(v)
+	if err != nil {
+		fatal("Redact failed: %v", err)
+	}
+	printJSON(out)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	appie "github.com/gwillem/appie-go"
+)
+
+// reorderLine is one stored product of a past meal and what became of it.
+type reorderLine struct {
+	Ingredient string  `json:"ingredient"`
+	ProductID  int     `json:"productId"`
+	Title      string  `json:"title,omitempty"`
+	Price      float64 `json:"price,omitempty"`
+	IsBonus    bool    `json:"isBonus,omitempty"`
+	Status     string  `json:"status"`
+	ReplacedID int     `json:"replacedId,omitempty"`
+	Error      string  `json:"error,omitempty"`
+
+	product appie.Product
+}
+
+// findApprovedMeals selects approved meals by recipe ID (latest approval) or
+// by date (every meal approved that day).
+func findApprovedMeals(h *mealHistory, ref string) []approvedMeal {
+	if _, err := time.Parse(dateLayout, ref); err == nil {
+		var meals []approvedMeal
+		for _, m := range h.Approved {
+			if m.Date == ref {
+				meals = append(meals, m)
+			}
+		}
+		return meals
+	}
+	id, err := strconv.Atoi(ref)
+	if err != nil {
+		return nil
+	}
+	var latest *approvedMeal
+	for i, m := range h.Approved {
+		if m.RecipeID == id && (latest == nil || m.Date >= latest.Date) {
+			latest = &h.Approved[i]
+		}
+	}
+	if latest == nil {
+		return nil
+	}
+	return []approvedMeal{*latest}
+}
+
+// checkReorderLines looks up every stored product and replaces the ones that
+// no longer exist or cannot be ordered with the first orderable search
+// result for the ingredient. Any other lookup failure (login, network, 5xx)
+// is returned rather than read as a dead product.
+func checkReorderLines(ctx context.Context, client *appie.Client, safety *safetyFilter, lines []reorderLine) error {
+	errs := make([]error, len(lines))
+	parallel(len(lines), 4, func(i int) {
+		l := &lines[i]
+		d, err := getProductDetails(ctx, client, l.ProductID)
+		switch {
+		case err != nil && !isNotFound(err):
+			errs[i] = err
+			return
+		case err == nil && (d.IsOrderable || d.IsAvailable):
+			l.Title, l.Price, l.IsBonus, l.Status = d.Title, d.Price.Now, d.IsBonus, "ok"
+			l.product = d.Product
+			return
+		}
+
+		// Skip the cache: it may be what pointed at the dead product.
+		resolver := &productResolver{client: client, cache: &productCache{}, safety: safety}
+		sub, serr := resolver.resolve(ctx, l.Ingredient)
+		if serr != nil || sub.ID == l.ProductID {
+			l.Status = "unavailable"
+			if err != nil {
+				l.Error = fmt.Sprintf("no product with id %d", l.ProductID)
+			}
+			return
+		}
+		l.ReplacedID, l.ProductID, l.Title, l.Status = l.ProductID, sub.ID, sub.Title, "substituted"
+		if d, err := getProductDetails(ctx, client, sub.ID); err == nil {
+			l.Price, l.IsBonus, l.product = d.Price.Now, d.IsBonus, d.Product
+		}
+	})
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runReorderMeal(ctx context.Context, configPath string, argv []string) {
+	args := parseArgs(argv, "order", "dry-run", "pantry")
+	if len(args.pos) < 1 {
+		fmt.Fprintln(os.Stderr, "Usage: appie-cli reorder-meal <recipe-id|YYYY-MM-DD> [--order] [--dry-run] [--no-pantry]")
+		os.Exit(1)
+	}
+	h, err := loadMealHistory()
+	if err != nil {
+		fatal("Load history failed: %v", err)
+	}
+	meals := findApprovedMeals(h, args.arg(0))
+	if len(meals) == 0 {
+		fatal("No approved meal found for %s", args.arg(0))
+	}
+
+	var lines []reorderLine
+	var names []string
+	for _, m := range meals {
+		names = append(names, m.RecipeName)
+		ingredients := make([]string, 0, len(m.ProductIDs))
+		for name := range m.ProductIDs {
+			ingredients = append(ingredients, name)
+		}
+		sort.Strings(ingredients)
+		for _, name := range ingredients {
+			lines = append(lines, reorderLine{Ingredient: name, ProductID: m.ProductIDs[name]})
+		}
+	}
+	if len(lines) == 0 {
+		fatal("No product_ids stored for %s; record them with history approve --products", args.arg(0))
+	}
+
+	// As in plan-week, what pantry.json has in stock is not bought again.
+	var inPantry []string
+	if args.flag("pantry", true) {
+		stock, err := loadPantryStock()
+		if err != nil {
+			fatal("Load pantry failed: %v", err)
+		}
+		kept := lines[:0]
+		for _, l := range lines {
+			if stock.covers(l.Ingredient, l.ProductID) {
+				inPantry = append(inPantry, l.Ingredient)
+				continue
+			}
+			kept = append(kept, l)
+		}
+		lines = kept
+	}
+
+	client := mustAuth(ctx, configPath)
+	cfg := mustSkillConfig()
+	if err := checkReorderLines(ctx, client, newSafetyFilter(cfg), lines); err != nil {
+		fatal("Reorder failed: %v", err)
+	}
+
+	// The same product may serve several meals; order it once per meal.
+	qty := map[int]int{}
+	var ids []int
+	var known []appie.Product
+	var total float64
+	for _, l := range lines {
+		if l.Status == "unavailable" {
+			continue
+		}
+		if qty[l.ProductID] == 0 {
+			ids = append(ids, l.ProductID)
+			known = append(known, l.product)
+		}
+		qty[l.ProductID]++
+		total += l.Price
+	}
+	result := map[string]any{
+		"meals": names,
+		"items": lines,
+		"total": roundCents(total),
+	}
+	if len(inPantry) > 0 {
+		result["inPantry"] = inPantry
+	}
+	if len(ids) == 0 {
+		printJSON(result)
+		return
+	}
+
+	// Butcher items become notes on the list, also with --order: the order
+	// only takes products.
+	items := make([]appie.ListItem, 0, len(ids))
+	for _, id := range ids {
+		items = append(items, appie.ListItem{ProductID: id, Quantity: qty[id]})
+	}
+	items, subs, err := newButcherRules(cfg.ButcherItems).apply(ctx, client, items)
+	if err != nil {
+		fatal("Reorder failed: %v", err)
+	}
+	if len(subs) > 0 {
+		result["substitutions"] = subs
+	}
+	if args.flag("dry-run", false) {
+		printJSON(result)
+		return
+	}
+
+	if args.flag("order", false) {
+		var order []appie.OrderItem
+		var notes []appie.ListItem
+		for _, it := range items {
+			if it.ProductID == 0 {
+				notes = append(notes, it)
+				continue
+			}
+			order = append(order, appie.OrderItem{ProductID: it.ProductID, Quantity: it.Quantity})
+		}
+		if len(order) > 0 {
+			if err := client.AddToOrder(ctx, order); err != nil {
+				fatal("Add to order failed: %v", err)
+			}
+		}
+		if len(notes) > 0 {
+			if err := client.AddToShoppingList(ctx, notes); err != nil {
+				fatal("Add to list failed: %v", err)
+			}
+		}
+	} else {
+		if err := client.AddToShoppingList(ctx, items); err != nil {
+			fatal("Add to list failed: %v", err)
+		}
+	}
+	recordAdditions(ctx, client, "reorder-meal", listQuantities(items), known...)
+	result["ok"] = true
+	result["added"] = len(items)
+	printJSON(result)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	appie "github.com/gwillem/appie-go"
+)
+
+// reorderServer answers product lookups with the status per product ID and
+// every search with product 7.
+func reorderServer(t *testing.T, status map[string]int) *appie.Client {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/search/") {
+			w.Write([]byte(`{"products":[{"webshopId":7,"title":"AH Vervanger","isOrderable":true}]}`))
+			return
+		}
+		id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		code, ok := status[id]
+		if !ok {
+			code = http.StatusOK
+		}
+		if code != http.StatusOK {
+			http.Error(w, `{"message":"nope"}`, code)
+			return
+		}
+		w.Write([]byte(`{"productCard":{"webshopId":` + id + `,"title":"Product ` + id + `","currentPrice":1.5,"isOrderable":true}}`))
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("APPIE_REST_URL", srv.URL)
+	return appie.New(appie.WithBaseURL(srv.URL), appie.WithTokens("token", ""))
+}
+
+func TestCheckReorderLines(t *testing.T) {
+	tests := []struct {
+		status  int
+		want    string // line status, "error" when the check fails
+		product int
+	}{
+		{200, "ok", 1},
+		{404, "substituted", 7},
+		{401, "error", 1},
+		{403, "error", 1},
+		{503, "error", 1},
+	}
+	for _, tt := range tests {
+		client := reorderServer(t, map[string]int{"1": tt.status})
+		lines := []reorderLine{{Ingredient: "kipfilet", ProductID: 1}}
+		err := checkReorderLines(context.Background(), client, &safetyFilter{}, lines)
+		if tt.want == "error" {
+			if err == nil {
+				t.Errorf("%d: no error, line %+v", tt.status, lines[0])
+			}
+			if lines[0].Status == "substituted" {
+				t.Errorf("%d: product substituted", tt.status)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: %v", tt.status, err)
+			continue
+		}
+		if l := lines[0]; l.Status != tt.want || l.ProductID != tt.product {
+			t.Errorf("%d: line %+v, want %s with product %d", tt.status, l, tt.want, tt.product)
+		}
+	}
+}
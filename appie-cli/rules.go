@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	appie "github.com/gwillem/appie-go"
+)
+
+// butcherSynonyms expands common butcher_items entries to the product names
+// they cover at AH. Terms match whole words only, so an entry like "kip"
+// needs its compounds ("kipfilet", "kippendij") listed here; compounds not
+// listed ("kippensoep", "kippenbouillon", "gehaktkruiden") stay AH products.
+var butcherSynonyms = map[string][]string{
+	"kip":          {"kipfilet", "kippendij", "kippenbout", "kippenpoot", "kippenvleugel", "kipdrumstick", "drumstick", "kipgehakt", "kipschnitzel", "kipreepjes", "kipblokjes", "kiphaasjes", "hele kip"},
+	"kipfilet":     {"kipfilet", "kipreepjes", "kipblokjes", "kiphaasjes"},
+	"gehakt":       {"gehakt", "rundergehakt", "varkensgehakt", "kipgehakt", "lamsgehakt", "half-om-half", "half om half", "gehaktballen", "hamburger"},
+	"rundvlees":    {"rundergehakt", "biefstuk", "runderlappen", "sukadelappen", "riblappen", "entrecote", "ossenhaas", "rosbief", "runderstoof", "stoofvlees", "runderreepjes", "ribeye", "rib-eye"},
+	"biefstuk":     {"biefstuk", "kogelbiefstuk", "ossenhaas", "entrecote"},
+	"varkensvlees": {"varkensgehakt", "varkenshaas", "varkensfilet", "karbonade", "speklap", "procureur", "hamlap", "varkensreepjes", "spareribs", "fricandeau", "schnitzel"},
+	"worst":        {"worst", "rookworst", "braadworst", "saucijs", "chipolata", "boerenworst"},
+	"lam":          {"lamsvlees", "lamsgehakt", "lamskotelet", "lamsrack", "lamsbout"},
+	"spek":         {"spekjes", "spekreepjes", "ontbijtspek", "buikspek"},
+}
+
+// butcherNotePrefix marks free text items that should be bought at the butcher.
+const butcherNotePrefix = "🥩 Slager: "
+
+// butcherRule is a single butcher_items entry with its expanded match terms.
+type butcherRule struct {
+	item  string
+	terms []string
+}
+
+// butcherRules routes meat that the user buys elsewhere away from AH products.
+type butcherRules []butcherRule
+
+// substitution reports a list item that was rewritten by a rule.
+type substitution struct {
+	ProductID     int    `json:"productId,omitempty"`
+	Original      string `json:"original"`
+	ReplacementID int    `json:"replacementId,omitempty"`
+	Replacement   string `json:"replacement"`
+	Quantity      int    `json:"quantity"`
+	Rule          string `json:"rule"`
+}
+
+// newButcherRules builds rules from config.json's butcher_items.
+func newButcherRules(items []string) butcherRules {
+	var rules butcherRules
+	for _, item := range items {
+		key := strings.ToLower(strings.TrimSpace(item))
+		if key == "" {
+			continue
+		}
+		terms := []string{key}
+		terms = append(terms, butcherSynonyms[key]...)
+		rules = append(rules, butcherRule{item: item, terms: terms})
+	}
+	return rules
+}
+
+// match reports which butcher_items entry covers a product. Products with a
+// known category outside meat and fish are never matched, and neither are
+// vegetarian products ("AH Vegetarische kipstukjes"), which share the meat
+// category at AH.
+func (r butcherRules) match(title, category string) (string, bool) {
+	if category != "" && !isMeatCategory(category) {
+		return "", false
+	}
+	title = strings.ToLower(title)
+	for _, v := range meatFreeWords {
+		if containsWordPrefix(title, v) {
+			return "", false
+		}
+	}
+	for _, rule := range r {
+		for _, term := range rule.terms {
+			if containsPhrase(title, term) {
+				return rule.item, true
+			}
+		}
+	}
+	return "", false
+}
+
+// apply rewrites every list item covered by a rule into a butcher note.
+// Product titles and categories are looked up in one request; free text
+// items are matched on their text.
+func (r butcherRules) apply(ctx context.Context, client *appie.Client, items []appie.ListItem) ([]appie.ListItem, []substitution, error) {
+	if len(r) == 0 {
+		return items, nil, nil
+	}
+
+	var ids []int
+	for _, item := range items {
+		if item.ProductID > 0 {
+			ids = append(ids, item.ProductID)
+		}
+	}
+	products := map[int]appie.Product{}
+	if len(ids) > 0 {
+		found, err := client.GetProductsByIDs(ctx, ids)
+		if err != nil {
+			return nil, nil, fmt.Errorf("butcher check: %w", err)
+		}
+		for _, p := range found {
+			products[p.ID] = p
+		}
+	}
+
+	var subs []substitution
+	out := make([]appie.ListItem, 0, len(items))
+	for _, item := range items {
+		if item.ProductID == 0 {
+			if !isButcherNote(item.Name) {
+				if rule, ok := r.match(item.Name, ""); ok {
+					note := butcherNotePrefix + item.Name
+					subs = append(subs, substitution{Original: item.Name, Replacement: note, Quantity: item.Quantity, Rule: rule})
+					item.Name = note
+				}
+			}
+			out = append(out, item)
+			continue
+		}
+
+		p, ok := products[item.ProductID]
+		if !ok {
+			out = append(out, item)
+			continue
+		}
+		rule, ok := r.match(p.Title, p.Category)
+		if !ok {
+			out = append(out, item)
+			continue
+		}
+		note := butcherNotePrefix + stripBrand(p.Title, p.Brand)
+		subs = append(subs, substitution{ProductID: p.ID, Original: p.Title, Replacement: note, Quantity: item.Quantity, Rule: rule})
+		out = append(out, appie.ListItem{Name: note, Quantity: item.Quantity})
+	}
+	return out, subs, nil
+}
+
+// meatFreeWords mark meat substitutes, matched at the start of a word
+// ("vegetarische", "vegaburger", "plantaardige").
+var meatFreeWords = []string{"vega", "plantaardig", "vleesvervanger"}
+
+func isMeatCategory(category string) bool {
+	c := strings.ToLower(category)
+	return strings.Contains(c, "vlees") || strings.Contains(c, "kip") || strings.Contains(c, "vis")
+}
+
+func isButcherNote(text string) bool {
+	return strings.Contains(strings.ToLower(text), "slager")
+}
+
+// stripBrand removes a leading brand name from a product title
+// ("AH Kipfilet" -> "Kipfilet").
+func stripBrand(title, brand string) string {
+	if brand != "" && strings.HasPrefix(strings.ToLower(title), strings.ToLower(brand)+" ") {
+		return strings.TrimSpace(title[len(brand):])
+	}
+	return title
+}
+
+// containsWordPrefix reports whether term occurs in s at the start of a word.
+func containsWordPrefix(s, term string) bool {
+	for i := 0; ; {
+		idx := strings.Index(s[i:], term)
+		if idx < 0 {
+			return false
+		}
+		pos := i + idx
+		if pos == 0 {
+			return true
+		}
+		if prev, _ := utf8.DecodeLastRuneInString(s[:pos]); !unicode.IsLetter(prev) {
+			return true
+		}
+		i = pos + 1
+	}
+}
+
+// containsPhrase reports whether term occurs in s as a whole word or words
+// ("hele kip"), allowing a plural ending ("kipfilets", "kippendijen").
+func containsPhrase(s, term string) bool {
+	for i := 0; ; {
+		idx := strings.Index(s[i:], term)
+		if idx < 0 {
+			return false
+		}
+		pos := i + idx
+		prev, _ := utf8.DecodeLastRuneInString(s[:pos])
+		rest := s[pos+len(term):]
+		for _, plural := range []string{"en", "'s", "s"} {
+			if r, ok := strings.CutPrefix(rest, plural); ok {
+				if next, _ := utf8.DecodeRuneInString(r); r == "" || !unicode.IsLetter(next) {
+					rest = r
+					break
+				}
+			}
+		}
+		next, _ := utf8.DecodeRuneInString(rest)
+		if (pos == 0 || !unicode.IsLetter(prev)) && (rest == "" || !unicode.IsLetter(next)) {
+			return true
+		}
+		i = pos + 1
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import "testing"
+
+func TestButcherRulesMatch(t *testing.T) {
+	rules := newButcherRules([]string{"kip", "gehakt", "biefstuk"})
+	tests := []struct {
+		title    string
+		category string
+		want     string
+	}{
+		{"AH Kipfilet", "Vlees, kip, vis, vega", "kip"},
+		{"AH Kipfilets 2 stuks", "", "kip"},
+		{"AH Kippendijen", "Vlees, kip, vis, vega", "kip"},
+		{"Hele kip", "", "kip"},
+		{"kip", "", "kip"},
+		{"AH Rundergehakt", "Vlees, kip, vis, vega", "gehakt"},
+		{"AH Half-om-half gehakt", "", "gehakt"},
+		{"Gehaktballen", "", "gehakt"},
+		{"AH Kogelbiefstuk", "Vlees, kip, vis, vega", "biefstuk"},
+
+		// compounds that are not meat
+		{"AH Kippensoep", "", ""},
+		{"Knorr Kippenbouillonblokje", "", ""},
+		{"Verstegen Gehaktkruiden", "", ""},
+		{"Kippensoep", "Soepen, sauzen, kruiden, olie", ""},
+
+		// meat substitutes in the meat aisle
+		{"AH Vegetarische kipstukjes", "Vlees, kip, vis, vega", ""},
+		{"AH Vega gehakt", "Vlees, kip, vis, vega", ""},
+		{"Plantaardige kipfilet", "", ""},
+
+		// known category outside meat
+		{"AH Kipfilet", "Soepen, sauzen, kruiden, olie", ""},
+	}
+	for _, tt := range tests {
+		got, ok := rules.match(tt.title, tt.category)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("match(%q, %q) = %q, %v; want %q", tt.title, tt.category, got, ok, tt.want)
+		}
+	}
+}
+
+func TestContainsPhrase(t *testing.T) {
+	tests := []struct {
+		s, term string
+		want    bool
+	}{
+		{"ah kipfilet", "kipfilet", true},
+		{"ah kipfilets", "kipfilet", true},
+		{"kippendijen naturel", "kippendij", true},
+		{"hele kip", "hele kip", true},
+		{"hele kip 1,4 kg", "hele kip", true},
+		{"kippensoep", "kip", false},
+		{"gehaktkruiden", "gehakt", false},
+		{"rundergehakt", "gehakt", false},
+		{"gehakt, 500g", "gehakt", true},
+	}
+	for _, tt := range tests {
+		if got := containsPhrase(tt.s, tt.term); got != tt.want {
+			t.Errorf("containsPhrase(%q, %q) = %v, want %v", tt.s, tt.term, got, tt.want)
+		}
+	}
+}
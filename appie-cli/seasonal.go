@@ -0,0 +1,123 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// seasonalCalendar is the Dutch produce calendar: the months (1-12) in
+// which each vegetable or fruit is harvested or sold from Dutch storage,
+// after Milieu Centraal's groente- en fruitkalender. Keys are stemmed on
+// load the way ingredient names are.
+//
+//go:embed seasonal.json
+var seasonalCalendar []byte
+
+var seasonalMonths = loadSeasonalCalendar()
+
+func loadSeasonalCalendar() map[string][]int {
+	var raw map[string][]int
+	if err := json.Unmarshal(seasonalCalendar, &raw); err != nil {
+		panic("seasonal.json: " + err.Error())
+	}
+	months := map[string][]int{}
+	for name, m := range raw {
+		months[strings.Join(indexTerms(name), " ")] = m
+	}
+	return months
+}
+
+// seasonalProduce returns the calendar entry an ingredient name refers to,
+// or "" when it is not Dutch seasonal produce. Multi-word entries ("rode
+// kool") match consecutive words; single words also match as the end of a
+// compound ("kropsla", "trostomaat"). The longest match wins, so "snijbiet"
+// is not read as "biet"; between matches of the same length the first in
+// alphabetical order does.
+func seasonalProduce(name string) string {
+	terms := indexTerms(name)
+	text := " " + strings.Join(terms, " ") + " "
+	best := ""
+	for key := range seasonalMonths {
+		if len(key) < len(best) || len(key) == len(best) && key > best {
+			continue
+		}
+		if strings.Contains(text, " "+key+" ") || strings.Contains(text, " "+strings.ReplaceAll(key, " ", "")+" ") {
+			best = key
+			continue
+		}
+		if len(key) < 3 || strings.Contains(key, " ") {
+			continue
+		}
+		for _, t := range terms {
+			if len(t) > len(key) && strings.HasSuffix(t, key) {
+				best = key
+				break
+			}
+		}
+	}
+	return best
+}
+
+// inSeason reports whether produce is in season in month.
+func inSeason(produce string, month time.Month) bool {
+	return slices.Contains(seasonalMonths[produce], int(month))
+}
+
+// yearRound reports whether produce is in season all year (onions,
+// potatoes), which says nothing about a recipe's seasonality.
+func yearRound(produce string) bool {
+	return len(seasonalMonths[produce]) == 12
+}
+
+// seasonality marks a recipe's produce as in or out of season. Share is the
+// in-season part of the produce that has a season; year-round produce is
+// listed but does not count. A recipe without seasonal produce has share 0.
+type seasonality struct {
+	Month       int      `json:"month"`
+	Share       float64  `json:"share"`
+	InSeason    []string `json:"inSeason"`
+	OutOfSeason []string `json:"outOfSeason"`
+}
+
+func recipeSeasonality(d *recipeDetails, month time.Month) *seasonality {
+	if month == 0 {
+		month = time.Now().Month()
+	}
+	s := &seasonality{Month: int(month), InSeason: []string{}, OutOfSeason: []string{}}
+	var in, counted int
+	seen := map[string]bool{}
+	for _, ing := range d.Ingredients {
+		produce := seasonalProduce(ing.name())
+		if produce == "" || seen[produce] {
+			continue
+		}
+		seen[produce] = true
+		if inSeason(produce, month) {
+			s.InSeason = append(s.InSeason, ing.name())
+		} else {
+			s.OutOfSeason = append(s.OutOfSeason, ing.name())
+		}
+		if !yearRound(produce) {
+			counted++
+			if inSeason(produce, month) {
+				in++
+			}
+		}
+	}
+	if counted > 0 {
+		s.Share = roundCents(float64(in) / float64(counted))
+	}
+	return s
+}
+
+// sortBySeason orders matches by seasonal share, keeping the search order
+// between equal shares.
+func sortBySeason(matches []recipeMatch) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Seasonal.Share > matches[j].Seasonal.Share
+	})
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSeasonalProduce(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"witte asperges", "asperge"},
+		{"2 uien", "ui"},
+		{"kropsla", "sla"},
+		{"cherrytomaatjes", "tomaat"},
+		{"rode kool", "rode kool"},
+		{"rodekool", "rode kool"},
+		{"snijbiet", "snijbiet"},
+		{"rode bieten", "biet"},
+		{"spruitjes", "spruit"},
+		{"peer prei", "peer"},
+		{"prei peer", "peer"},
+		{"paprikapoeder", ""},
+		{"kipfilet", ""},
+	}
+	for _, tt := range tests {
+		if got := seasonalProduce(tt.name); got != tt.want {
+			t.Errorf("seasonalProduce(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRecipeSeasonality(t *testing.T) {
+	d := &recipeDetails{Title: "Voorjaarssalade"}
+	for _, name := range []string{"witte asperges", "tomaten", "cherrytomaatjes", "pompoen", "ui", "kipfilet"} {
+		d.Ingredients = append(d.Ingredients, recipeIngredient{Name: recipeNoun{Singular: name}})
+	}
+	tests := []struct {
+		month time.Month
+		share float64
+		in    []string
+		out   []string
+	}{
+		// Onion is year-round: listed, but not counted in the share.
+		{time.May, 0.67, []string{"witte asperges", "tomaten", "ui"}, []string{"pompoen"}},
+		{time.October, 0.67, []string{"tomaten", "pompoen", "ui"}, []string{"witte asperges"}},
+		{time.January, 0, []string{"ui"}, []string{"witte asperges", "tomaten", "pompoen"}},
+	}
+	for _, tt := range tests {
+		s := recipeSeasonality(d, tt.month)
+		if s.Share != tt.share || !slices.Equal(s.InSeason, tt.in) || !slices.Equal(s.OutOfSeason, tt.out) {
+			t.Errorf("%s: share %g, in %v, out %v; want %g, %v, %v", tt.month, s.Share, s.InSeason, s.OutOfSeason, tt.share, tt.in, tt.out)
+		}
+	}
+}
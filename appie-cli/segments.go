@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	appie "github.com/gwillem/appie-go"
+)
+
+// bonusSegment is one bonus promotion ("2e halve prijs", "25% korting")
+// grouping the products it applies to.
+type bonusSegment struct {
+	ID           string  `json:"id"`
+	Title        string  `json:"title"`
+	Description  string  `json:"description,omitempty"`
+	Category     string  `json:"category,omitempty"`
+	Discount     string  `json:"discount,omitempty"`
+	Price        float64 `json:"price,omitempty"`
+	WasPrice     float64 `json:"wasPrice,omitempty"`
+	StartDate    string  `json:"startDate,omitempty"`
+	EndDate      string  `json:"endDate,omitempty"`
+	ProductCount int     `json:"productCount"`
+	ProductIDs   []int   `json:"productIds"`
+}
+
+// gqlBonusSegment is the GraphQL shape of a BonusSegment.
+type gqlBonusSegment struct {
+	ID           string `json:"id"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	Category     string `json:"category"`
+	ProductCount int    `json:"productCount"`
+	ProductIDs   []int  `json:"productIds"`
+	Price        *struct {
+		Now *struct{ Amount float64 } `json:"now"`
+		Was *struct{ Amount float64 } `json:"was"`
+	} `json:"price"`
+	Discount *struct {
+		Title string `json:"title"`
+	} `json:"discount"`
+	Availability *struct {
+		StartDate string `json:"startDate"`
+		EndDate   string `json:"endDate"`
+	} `json:"availability"`
+}
+
+func (s gqlBonusSegment) typed() bonusSegment {
+	out := bonusSegment{
+		ID:           s.ID,
+		Title:        s.Title,
+		Description:  s.Description,
+		Category:     s.Category,
+		ProductCount: s.ProductCount,
+		ProductIDs:   s.ProductIDs,
+	}
+	if out.ProductIDs == nil {
+		out.ProductIDs = []int{}
+	}
+	if s.Price != nil && s.Price.Now != nil {
+		out.Price = s.Price.Now.Amount
+	}
+	if s.Price != nil && s.Price.Was != nil {
+		out.WasPrice = s.Price.Was.Amount
+	}
+	if s.Discount != nil {
+		out.Discount = s.Discount.Title
+	}
+	if s.Availability != nil {
+		out.StartDate, out.EndDate = s.Availability.StartDate, s.Availability.EndDate
+	}
+	return out
+}
+
+// getBonusSegments fetches the current bonus segments via GraphQL
+func getBonusSegments(ctx context.Context, client *appie.Client) ([]bonusSegment, error) {
+	var data struct {
+		BonusSegments []gqlBonusSegment `json:"bonusSegments"`
+	}
+	if err := runCatalogQuery(ctx, client, "bonus-segments", nil, &data); err != nil {
+		return nil, err
+	}
+	segments := make([]bonusSegment, 0, len(data.BonusSegments))
+	for _, s := range data.BonusSegments {
+		segments = append(segments, s.typed())
+	}
+	return segments, nil
+}
+
+func runBonusSegment(ctx context.Context, configPath string, argv []string) {
+	if len(argv) < 1 {
+		fmt.Fprintln(os.Stderr, "Usage: appie-cli bonus-segment <segment-id>")
+		os.Exit(1)
+	}
+	client := mustAuth(ctx, configPath)
+	segments, err := getBonusSegments(ctx, client)
+	if err != nil {
+		fatal("Get bonus segments failed: %v", err)
+	}
+	for _, s := range segments {
+		if s.ID != argv[0] {
+			continue
+		}
+		products := []appie.Product{}
+		if len(s.ProductIDs) > 0 {
+			products, err = client.GetProductsByIDs(ctx, s.ProductIDs)
+			if err != nil {
+				fatal("Get products failed: %v", err)
+			}
+		}
+		printJSON(map[string]any{
+			"segment":  s,
+			"products": products,
+		})
+		return
+	}
+	fatal("Bonus segment %s not found", argv[0])
+}
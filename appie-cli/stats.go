@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// spendLine is one purchased (receipts) or listed (list additions) product.
+type spendLine struct {
+	Date        string  `json:"date"`
+	Description string  `json:"description"`
+	ProductID   int     `json:"productId,omitempty"`
+	Brand       string  `json:"brand,omitempty"`
+	Category    string  `json:"category,omitempty"`
+	Quantity    int     `json:"quantity"`
+	Amount      float64 `json:"amount"`
+	Bonus       bool    `json:"bonus"`
+	Savings     float64 `json:"savings,omitempty"`
+}
+
+// spendBucket is the spend aggregated under one key (a week, category, ...).
+type spendBucket struct {
+	Key     string  `json:"key"`
+	Total   float64 `json:"total"`
+	Savings float64 `json:"savings,omitempty"`
+	Lines   int     `json:"lines"`
+}
+
+// spendingReport is the output of stats spending.
+type spendingReport struct {
+	Source       string        `json:"source"`
+	From         string        `json:"from,omitempty"`
+	To           string        `json:"to,omitempty"`
+	Total        float64       `json:"total"`
+	BonusSavings float64       `json:"bonusSavings"`
+	ByWeek       []spendBucket `json:"byWeek"`
+	ByMonth      []spendBucket `json:"byMonth"`
+	ByCategory   []spendBucket `json:"byCategory"`
+	ByBrand      []spendBucket `json:"byBrand"`
+	ByBonus      []spendBucket `json:"byBonus"`
+}
+
+// receiptSpendLines flattens archived receipts. Receipts carry no brand or
+// category, so those come from the list additions for the same product ID or
+// title. A product is bonus when the kassabon marks it B or it was added to
+// the list in the bonus; a "BONUS ..." line with a negative amount is the
+// discount on the product above it. Receipts without items count with their
+// total only.
+func receiptSpendLines(h *purchaseHistory) []spendLine {
+	byID := map[int]listAddition{}
+	byTitle := map[string]listAddition{}
+	for _, a := range h.ListAdditions {
+		byID[a.ProductID] = a
+		byTitle[strings.ToLower(a.Title)] = a
+	}
+	var lines []spendLine
+	for _, r := range h.Receipts {
+		if len(r.Items) == 0 {
+			// A summary from the receipts list: only the total is known.
+			lines = append(lines, spendLine{Date: receiptDay(r.Date), Description: r.StoreName, Amount: r.TotalAmount})
+			continue
+		}
+		first := len(lines)
+		for _, it := range r.Items {
+			discount := it.Amount < 0 && strings.HasPrefix(strings.ToLower(it.Description), "bonus")
+			if discount && len(lines) > first {
+				prev := &lines[len(lines)-1]
+				prev.Amount = roundCents(prev.Amount + it.Amount)
+				prev.Savings = roundCents(prev.Savings - it.Amount)
+				prev.Bonus = true
+				continue
+			}
+			l := spendLine{
+				Date:        receiptDay(r.Date),
+				Description: it.Description,
+				ProductID:   it.ProductID,
+				Quantity:    it.Quantity,
+				Amount:      it.Amount,
+				Bonus:       it.Bonus,
+			}
+			if discount {
+				// No product above it on this receipt.
+				l.Bonus, l.Savings, l.Category = true, -it.Amount, "Bonus korting"
+				lines = append(lines, l)
+				continue
+			}
+			a, ok := byID[it.ProductID]
+			if !ok || it.ProductID == 0 {
+				a, ok = byTitle[strings.ToLower(it.Description)]
+			}
+			if ok {
+				l.ProductID, l.Brand, l.Category = a.ProductID, a.Brand, a.Category
+				l.Bonus = l.Bonus || a.IsBonus
+			}
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// listSpendLines turns list additions into planned spend, with the bonus
+// discount as savings.
+func listSpendLines(h *purchaseHistory) []spendLine {
+	lines := make([]spendLine, 0, len(h.ListAdditions))
+	for _, a := range h.ListAdditions {
+		l := spendLine{
+			Date:        a.Date,
+			Description: a.Title,
+			ProductID:   a.ProductID,
+			Brand:       a.Brand,
+			Category:    a.Category,
+			Quantity:    a.Quantity,
+			Amount:      roundCents(a.Price * float64(a.Quantity)),
+			Bonus:       a.IsBonus,
+		}
+		if a.IsBonus && a.WasPrice > a.Price {
+			l.Savings = roundCents((a.WasPrice - a.Price) * float64(a.Quantity))
+		}
+		lines = append(lines, l)
+	}
+	return lines
+}
+
+// receiptDay reduces a receipt timestamp to YYYY-MM-DD.
+func receiptDay(date string) string {
+	if len(date) >= len(dateLayout) {
+		return date[:len(dateLayout)]
+	}
+	return date
+}
+
+// aggregate groups lines by key. Time buckets stay in chronological order,
+// the others are sorted by total, largest first.
+func aggregate(lines []spendLine, chronological bool, key func(spendLine) string) []spendBucket {
+	idx := map[string]int{}
+	buckets := []spendBucket{}
+	for _, l := range lines {
+		k := key(l)
+		if k == "" {
+			k = "Onbekend"
+		}
+		i, ok := idx[k]
+		if !ok {
+			i = len(buckets)
+			idx[k] = i
+			buckets = append(buckets, spendBucket{Key: k})
+		}
+		buckets[i].Total += l.Amount
+		buckets[i].Savings += l.Savings
+		buckets[i].Lines++
+	}
+	for i := range buckets {
+		buckets[i].Total = roundCents(buckets[i].Total)
+		buckets[i].Savings = roundCents(buckets[i].Savings)
+	}
+	sort.SliceStable(buckets, func(i, j int) bool {
+		if chronological {
+			return buckets[i].Key < buckets[j].Key
+		}
+		if buckets[i].Total != buckets[j].Total {
+			return buckets[i].Total > buckets[j].Total
+		}
+		return buckets[i].Key < buckets[j].Key
+	})
+	return buckets
+}
+
+func isoWeek(date string) string {
+	t, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return ""
+	}
+	y, w := t.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", y, w)
+}
+
+func spendingStats(source string, lines []spendLine) *spendingReport {
+	r := &spendingReport{Source: source}
+	for _, l := range lines {
+		r.Total += l.Amount
+		r.BonusSavings += l.Savings
+		if r.From == "" || l.Date < r.From {
+			r.From = l.Date
+		}
+		if l.Date > r.To {
+			r.To = l.Date
+		}
+	}
+	r.Total, r.BonusSavings = roundCents(r.Total), roundCents(r.BonusSavings)
+	r.ByWeek = aggregate(lines, true, func(l spendLine) string { return isoWeek(l.Date) })
+	r.ByMonth = aggregate(lines, true, func(l spendLine) string {
+		if len(l.Date) < 7 {
+			return ""
+		}
+		return l.Date[:7]
+	})
+	r.ByCategory = aggregate(lines, false, func(l spendLine) string { return l.Category })
+	r.ByBrand = aggregate(lines, false, func(l spendLine) string { return l.Brand })
+	r.ByBonus = aggregate(lines, false, func(l spendLine) string {
+		if l.Bonus {
+			return "bonus"
+		}
+		return "regular"
+	})
+	return r
+}
+
+// writeSpendCSV writes one row per line, for spreadsheets.
+func writeSpendCSV(w io.Writer, lines []spendLine) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"date", "week", "description", "product_id", "brand", "category", "quantity", "amount", "bonus", "savings"})
+	for _, l := range lines {
+		cw.Write([]string{
+			l.Date,
+			isoWeek(l.Date),
+			l.Description,
+			strconv.Itoa(l.ProductID),
+			l.Brand,
+			l.Category,
+			strconv.Itoa(l.Quantity),
+			strconv.FormatFloat(l.Amount, 'f', 2, 64),
+			strconv.FormatBool(l.Bonus),
+			strconv.FormatFloat(l.Savings, 'f', 2, 64),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func runStats(argv []string) {
+	if len(argv) < 1 || argv[0] != "spending" {
+		fmt.Fprintln(os.Stderr, "Usage: appie-cli stats spending [--since YYYY-MM-DD|4w] [--source receipts|list] [--csv [file|-]]")
+		os.Exit(1)
+	}
+	args := parseArgs(argv[1:])
+	h, err := loadPurchaseHistory()
+	if err != nil {
+		fatal("Load purchase history failed: %v", err)
+	}
+
+	// Receipts are what was actually paid; list additions are only a plan.
+	// Mixing both would count the same groceries twice.
+	source := args.str("source", "")
+	if source == "" {
+		source = "receipts"
+		if len(h.Receipts) == 0 {
+			source = "list"
+		}
+	}
+	var lines []spendLine
+	switch source {
+	case "receipts":
+		lines = receiptSpendLines(h)
+	case "list":
+		lines = listSpendLines(h)
+	default:
+		fatal("Unknown --source %q (use receipts or list)", source)
+	}
+
+	if v := args.str("since", ""); v != "" {
+		since, err := parseSince(v, time.Now())
+		if err != nil {
+			fatal("%v", err)
+		}
+		from := since.Format(dateLayout)
+		kept := lines[:0]
+		for _, l := range lines {
+			if l.Date >= from {
+				kept = append(kept, l)
+			}
+		}
+		lines = kept
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].Date < lines[j].Date })
+
+	if args.has("csv") {
+		// A bare --csv writes to stdout, like --csv -.
+		path := args.str("csv", "-")
+		w := io.Writer(os.Stdout)
+		if path != "-" {
+			f, err := os.Create(path)
+			if err != nil {
+				fatal("Write CSV failed: %v", err)
+			}
+			defer f.Close()
+			w = f
+		}
+		if err := writeSpendCSV(w, lines); err != nil {
+			fatal("Write CSV failed: %v", err)
+		}
+		if path == "-" {
+			return
+		}
+	}
+	printJSON(spendingStats(source, lines))
+}
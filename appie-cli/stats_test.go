@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestReceiptSpendLinesBonus(t *testing.T) {
+	r, err := parseReceiptText(kassabon)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Through JSON, as the receipt comes back from the archive.
+	data, err := json.Marshal(storedReceipt{receipt: *r, Source: "kassabon.pdf"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored storedReceipt
+	if err := json.Unmarshal(data, &stored); err != nil {
+		t.Fatal(err)
+	}
+	h := &purchaseHistory{Receipts: []storedReceipt{stored}}
+
+	lines := receiptSpendLines(h)
+	if len(lines) != 4 {
+		t.Fatalf("lines = %+v, want 4 products", lines)
+	}
+	if l := lines[2]; l.Description != "LAYS CHIPS PAPRIKA" || !l.Bonus || l.Amount != 1.44 || l.Savings != 0.75 {
+		t.Errorf("chips line = %+v, want bonus 1.44 with 0.75 savings", l)
+	}
+
+	report := spendingStats("receipts", lines)
+	want := []spendBucket{
+		{Key: "bonus", Total: 7.67, Savings: 1.99, Lines: 2},
+		{Key: "regular", Total: 3.27, Lines: 2},
+	}
+	if len(report.ByBonus) != len(want) {
+		t.Fatalf("byBonus = %+v, want %+v", report.ByBonus, want)
+	}
+	for i, w := range want {
+		if report.ByBonus[i] != w {
+			t.Errorf("byBonus[%d] = %+v, want %+v", i, report.ByBonus[i], w)
+		}
+	}
+	if report.Total != 10.94 || report.BonusSavings != 1.99 {
+		t.Errorf("total %.2f, savings %.2f; want 10.94, 1.99", report.Total, report.BonusSavings)
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	appie "github.com/gwillem/appie-go"
+)
+
+// substituteCandidate is a search hit scored against the product it would
+// replace.
+type substituteCandidate struct {
+	ProductID      int     `json:"productId"`
+	Title          string  `json:"title"`
+	Brand          string  `json:"brand,omitempty"`
+	Category       string  `json:"category,omitempty"`
+	UnitSize       string  `json:"unitSize,omitempty"`
+	Price          float64 `json:"price"`
+	UnitPrice      float64 `json:"unitPrice,omitempty"`
+	Unit           string  `json:"unit,omitempty"`
+	IsBonus        bool    `json:"isBonus,omitempty"`
+	BonusMechanism string  `json:"bonusMechanism,omitempty"`
+	Score          float64 `json:"score"`
+}
+
+// minSubstituteScore is the similarity below which a hit is a different
+// product rather than a substitute.
+const minSubstituteScore = 0.45
+
+var unitSizePattern = regexp.MustCompile(`(?i)(?:(\d+)\s*x\s*)?(\d+(?:[.,]\d+)?)\s*(kg|kilo|gram|g|liter|l|ml|cl|stuks|stuk|st)\b`)
+
+// parseUnitSize converts "500 g", "1,5 l" or "4 x 125 g" to an amount in g,
+// ml or pieces.
+func parseUnitSize(s string) (float64, string, bool) {
+	m := unitSizePattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, "", false
+	}
+	n, err := strconv.ParseFloat(strings.ReplaceAll(m[2], ",", "."), 64)
+	if err != nil {
+		return 0, "", false
+	}
+	if m[1] != "" {
+		k, _ := strconv.Atoi(m[1])
+		n *= float64(k)
+	}
+	switch strings.ToLower(m[3]) {
+	case "kg", "kilo":
+		return n * 1000, "g", true
+	case "gram", "g":
+		return n, "g", true
+	case "liter", "l":
+		return n * 1000, "ml", true
+	case "cl":
+		return n * 10, "ml", true
+	case "ml":
+		return n, "ml", true
+	default:
+		return n, "st", true
+	}
+}
+
+// similarity scores cand against orig from 0 to 1 on title words (weight
+// 0.5), category (0.2), subcategory, brand and package size (0.1 each).
+// Attributes unknown for orig, e.g. for a discontinued product known only by
+// name, are left out of the weighting.
+func similarity(orig, cand appie.Product) float64 {
+	a := matchTokens(stripBrand(orig.Title, orig.Brand))
+	b := matchTokens(stripBrand(cand.Title, cand.Brand))
+	shared := 0
+	for _, t := range a {
+		for _, u := range b {
+			if t == u {
+				shared++
+				break
+			}
+		}
+	}
+	score, weight := 0.0, 0.5
+	if union := len(a) + len(b) - shared; union > 0 {
+		score += 0.5 * float64(shared) / float64(union)
+	}
+	attr := func(w float64, known, match bool) {
+		if known {
+			weight += w
+			if match {
+				score += w
+			}
+		}
+	}
+	attr(0.2, orig.Category != "", strings.EqualFold(orig.Category, cand.Category))
+	attr(0.1, orig.SubCategory != "", strings.EqualFold(orig.SubCategory, cand.SubCategory))
+	attr(0.1, orig.Brand != "", strings.EqualFold(orig.Brand, cand.Brand))
+	if n1, u1, ok := parseUnitSize(orig.UnitSize); ok {
+		weight += 0.1
+		if n2, u2, ok := parseUnitSize(cand.UnitSize); ok && u1 == u2 {
+			score += 0.1 * math.Min(n1, n2) / math.Max(n1, n2)
+		}
+	}
+	return math.Round(score/weight*100) / 100
+}
+
+// findSubstitutes searches for products like orig and returns the available
+// ones similar enough to replace it, cheapest per unit first. Hits without a
+// comparable unit price follow, by similarity.
+func findSubstitutes(ctx context.Context, client *appie.Client, safety *safetyFilter, orig appie.Product) ([]substituteCandidate, error) {
+	query := stripBrand(orig.Title, orig.Brand)
+	if query == "" {
+		return nil, fmt.Errorf("no title known for product %d", orig.ID)
+	}
+	hits, err := client.SearchProducts(ctx, query, 20)
+	if err != nil {
+		return nil, err
+	}
+	hits, _ = safety.filterProducts(hits)
+
+	_, origUnit, _ := unitPrice(orig)
+	var out []substituteCandidate
+	for _, p := range hits {
+		if p.ID == orig.ID || !(p.IsOrderable || p.IsAvailable) {
+			continue
+		}
+		score := similarity(orig, p)
+		if score < minSubstituteScore {
+			continue
+		}
+		c := substituteCandidate{
+			ProductID:      p.ID,
+			Title:          p.Title,
+			Brand:          p.Brand,
+			Category:       p.Category,
+			UnitSize:       p.UnitSize,
+			Price:          p.Price.Now,
+			IsBonus:        p.IsBonus,
+			BonusMechanism: p.BonusMechanism,
+			Score:          score,
+		}
+		if up, unit, ok := unitPrice(p); ok && (origUnit == "" || unit == origUnit) {
+			c.UnitPrice, c.Unit = up, unit
+		}
+		out = append(out, c)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if (a.UnitPrice > 0) != (b.UnitPrice > 0) {
+			return a.UnitPrice > 0
+		}
+		if a.UnitPrice > 0 && a.Unit == b.Unit && a.UnitPrice != b.UnitPrice {
+			return a.UnitPrice < b.UnitPrice
+		}
+		return a.Score > b.Score
+	})
+	return out, nil
+}
+
+// knownProduct describes a product that GetProduct no longer returns, from
+// what the skill files remember about it: list additions in
+// purchase-history.json, weekly-basics.json and product-cache.json.
+func knownProduct(id int) appie.Product {
+	p := appie.Product{ID: id}
+	if h, err := loadPurchaseHistory(); err == nil {
+		for _, a := range h.ListAdditions {
+			if a.ProductID == id {
+				p.Title, p.Brand, p.Category = a.Title, a.Brand, a.Category
+			}
+		}
+	}
+	if p.Title != "" {
+		return p
+	}
+	if b, err := loadWeeklyBasics(); err == nil {
+		for _, item := range append(b.Weekly, b.Biweekly...) {
+			if item.ID == id {
+				p.Title = item.Name
+				return p
+			}
+		}
+	}
+	if c, err := loadProductCache(); err == nil {
+		for _, section := range []map[string]productRef{c.Basics, c.Ingredients} {
+			for name, ref := range section {
+				if int(ref) == id {
+					p.Title = name
+					return p
+				}
+			}
+		}
+	}
+	return p
+}
+
+func runSubstitute(ctx context.Context, configPath string, argv []string) {
+	args := parseArgs(argv, "safe")
+	id := args.argInt(0, 0)
+	if id <= 0 {
+		fmt.Fprintln(os.Stderr, "Usage: appie-cli substitute <product-id> [n] [--name \"product title\"] [--no-safe]")
+		os.Exit(1)
+	}
+	client := mustAnon(ctx, configPath)
+	cfg := mustSkillConfig()
+	safety := newSafetyFilter(cfg)
+	if !args.flag("safe", true) {
+		safety = &safetyFilter{}
+	}
+
+	orig, err := client.GetProduct(ctx, id)
+	status := "available"
+	if err != nil {
+		known := knownProduct(id)
+		orig, status = &known, "not found"
+	} else if !(orig.IsOrderable || orig.IsAvailable) {
+		status = "unavailable"
+	}
+	if name := args.str("name", ""); name != "" {
+		orig.Title, orig.Brand = name, ""
+	}
+	if orig.Title == "" {
+		fatal("Product %d not found and not in the skill files; pass --name \"product title\"", id)
+	}
+
+	cands, err := findSubstitutes(ctx, client, safety, *orig)
+	if err != nil {
+		fatal("Find substitutes failed: %v", err)
+	}
+	if n := args.argInt(1, 5); len(cands) > n {
+		cands = cands[:n]
+	}
+	if cands == nil {
+		cands = []substituteCandidate{}
+	}
+	printJSON(map[string]any{
+		"original": map[string]any{
+			"productId": id,
+			"title":     orig.Title,
+			"brand":     orig.Brand,
+			"unitSize":  orig.UnitSize,
+			"price":     orig.Price.Now,
+			"status":    status,
+		},
+		"substitutes": cands,
+	})
+}
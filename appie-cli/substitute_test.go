@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	appie "github.com/gwillem/appie-go"
+)
+
+func TestParseUnitSize(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    float64
+		unit string
+		ok   bool
+	}{
+		{"500 g", 500, "g", true},
+		{"1 kilo", 1000, "g", true},
+		{"1,5 l", 1500, "ml", true},
+		{"33 cl", 330, "ml", true},
+		{"4 x 125 g", 500, "g", true},
+		{"6 stuks", 6, "st", true},
+		{"per stuk", 0, "", false},
+	}
+	for _, tt := range tests {
+		n, unit, ok := parseUnitSize(tt.s)
+		if n != tt.n || unit != tt.unit || ok != tt.ok {
+			t.Errorf("parseUnitSize(%q) = %g %q %v, want %g %q %v", tt.s, n, unit, ok, tt.n, tt.unit, tt.ok)
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	melk := appie.Product{Title: "AH Halfvolle melk", Brand: "AH", Category: "Zuivel", SubCategory: "Melk", UnitSize: "1 l"}
+	tests := []struct {
+		name string
+		orig appie.Product
+		cand appie.Product
+		want float64
+	}{
+		{"same product", melk, melk, 1},
+		{"other brand, larger pack", melk,
+			appie.Product{Title: "Campina Halfvolle melk", Brand: "Campina", Category: "Zuivel", SubCategory: "Melk", UnitSize: "1,5 l"}, 0.87},
+		{"other product", melk,
+			appie.Product{Title: "AH Volkoren brood", Brand: "AH", Category: "Bakkerij", UnitSize: "800 g"}, 0.1},
+		// Known only by name: the title alone decides.
+		{"title only", appie.Product{Title: "AH Halfvolle melk"},
+			appie.Product{Title: "Campina Halfvolle melk", Brand: "Campina", Category: "Zuivel"}, 0.67},
+	}
+	for _, tt := range tests {
+		if got := similarity(tt.orig, tt.cand); got != tt.want {
+			t.Errorf("%s: similarity = %g, want %g", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSubstituteLines(t *testing.T) {
+	// A free text addition is logged with product ID 0: an unresolved name
+	// line must not pick it up as the product to replace.
+	t.Setenv("APPIE_SKILL_DIR", t.TempDir())
+	h := &purchaseHistory{ListAdditions: []listAddition{{ProductID: 0, Title: "AH Halfvolle melk"}}}
+	if err := h.save(); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"products":[{"webshopId":7,"title":"Campina Halfvolle melk","brand":"Campina","isOrderable":true}]}`))
+	}))
+	defer srv.Close()
+	client := appie.New(appie.WithBaseURL(srv.URL), appie.WithTokens("token", ""))
+
+	lines := []batchLine{
+		{Name: "zuurdesem", Qty: 1, Status: "unknown"},
+		{ID: 5, Qty: 2, Status: "unavailable", product: appie.Product{ID: 5, Title: "AH Halfvolle melk", Brand: "AH"}},
+	}
+	subs := substituteLines(context.Background(), client, &safetyFilter{}, lines)
+	if len(subs) != 1 || subs[0].ProductID != 5 || subs[0].ReplacementID != 7 {
+		t.Errorf("substitutions = %+v, want product 5 replaced by 7", subs)
+	}
+	if lines[0].Status != "unknown" || lines[0].ReplacementID != 0 {
+		t.Errorf("name line = %+v, want it left unknown", lines[0])
+	}
+	if lines[1].Status != "substituted" || lines[1].ReplacementID != 7 {
+		t.Errorf("id line = %+v, want substituted by 7", lines[1])
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	appie "github.com/gwillem/appie-go"
+)
+
+// recipeSuggestion is a recipe ranked by how much it uses current bonus deals.
+type recipeSuggestion struct {
+	ID               int          `json:"id"`
+	Title            string       `json:"title"`
+	Slug             string       `json:"slug"`
+	CookTime         int          `json:"cookTime"`
+	Servings         int          `json:"servings,omitempty"`
+	Tags             []string     `json:"tags,omitempty"`
+	Ingredients      int          `json:"ingredients"`
+	BonusIngredients int          `json:"bonusIngredients"`
+	Coverage         float64      `json:"coverage"`
+	EstimatedSavings float64      `json:"estimatedSavings"`
+	Matches          []bonusMatch `json:"matches"`
+	Seasonal         *seasonality `json:"seasonal"`
+
+	details *recipeDetails
+}
+
+// suggestRecipes searches recipes for each query and ranks them by estimated
+// bonus savings, then by the share of ingredients on bonus. With
+// filters.Seasonal the seasonal share comes first. Recipes found by several
+// queries are only ranked once.
+func suggestRecipes(ctx context.Context, client *appie.Client, cfg *skillConfig, queries []string, candidates int, filters recipeFilters, safety *safetyFilter) ([]recipeSuggestion, []exclusion, error) {
+	bonus, err := getBonusProductsTyped(ctx, client, 200)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bonus products: %w", err)
+	}
+	matcher := newBonusMatcher(bonus, newButcherRules(cfg.ButcherItems))
+
+	var found []recipeSummary
+	seen := map[int]bool{}
+	for _, q := range queries {
+		var result *recipeSearchResult
+		result, filters, err = searchRecipesFiltered(ctx, client, q, candidates, filters, false)
+		if err != nil {
+			return nil, nil, fmt.Errorf("search recipes: %w", err)
+		}
+		for _, r := range result.Result {
+			if !seen[r.ID] {
+				seen[r.ID] = true
+				found = append(found, r)
+			}
+		}
+	}
+	hits, excluded := selectRecipes(ctx, client, found, filters, safety, true, 0)
+
+	suggestions := make([]recipeSuggestion, 0, len(hits))
+	for _, h := range hits {
+		suggestions = append(suggestions, rankRecipe(h, matcher, filters.Month))
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if filters.Seasonal && a.Seasonal.Share != b.Seasonal.Share {
+			return a.Seasonal.Share > b.Seasonal.Share
+		}
+		if a.EstimatedSavings != b.EstimatedSavings {
+			return a.EstimatedSavings > b.EstimatedSavings
+		}
+		return a.Coverage > b.Coverage
+	})
+	return suggestions, excluded, nil
+}
+
+func rankRecipe(h recipeMatch, matcher *bonusMatcher, month time.Month) recipeSuggestion {
+	d := h.details
+	seasonal := h.Seasonal
+	if seasonal == nil {
+		seasonal = recipeSeasonality(d, month)
+	}
+	matches := matcher.matchRecipe(d)
+	var savings float64
+	for _, m := range matches {
+		savings += m.Savings
+	}
+	var coverage float64
+	if len(d.Ingredients) > 0 {
+		coverage = float64(len(matches)) / float64(len(d.Ingredients))
+	}
+	return recipeSuggestion{
+		ID:               h.ID,
+		Title:            h.Title,
+		Slug:             h.Slug,
+		CookTime:         h.CookTime,
+		Servings:         d.Servings,
+		Tags:             d.Tags,
+		Ingredients:      len(d.Ingredients),
+		BonusIngredients: len(matches),
+		Coverage:         roundCents(coverage),
+		EstimatedSavings: roundCents(savings),
+		Matches:          matches,
+		Seasonal:         seasonal,
+		details:          d,
+	}
+}
+
+func runSuggestRecipes(ctx context.Context, configPath string, argv []string) {
+	args := parseArgs(argv, "safe", "seasonal")
+	client := mustAuth(ctx, configPath)
+	cfg := mustSkillConfig()
+	n := args.argInt(1, 5)
+	safety := newSafetyFilter(cfg)
+	if !args.flag("safe", true) {
+		safety = &safetyFilter{}
+	}
+	candidates := args.num("candidates", 30)
+	if candidates < n {
+		candidates = n
+	}
+
+	suggestions, excluded, err := suggestRecipes(ctx, client, cfg, []string{args.arg(0)}, candidates, recipeFiltersFromArgs(args, cfg), safety)
+	if err != nil {
+		fatal("Suggest recipes failed: %v", err)
+	}
+	if len(suggestions) == 0 {
+		fmt.Fprintln(os.Stderr, "No recipes matched; try a broader query or --max-time 0")
+	}
+	if len(suggestions) > n {
+		suggestions = suggestions[:n]
+	}
+	printJSON(map[string]any{
+		"suggestions": suggestions,
+		"excluded":    excluded,
+	})
+}